@@ -0,0 +1,152 @@
+package cas
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+// AttributeNormalizer renames the attributes of an AuthenticationResponse so
+// applications see the same names regardless of the CAS server style.
+//
+// Names are normalised by stripping prefixes, then applying Rename, then
+// Aliases. Values of attributes normalised to the same name are merged.
+//
+// An AttributeNormalizer may be loaded from YAML:
+//
+//	stripNamespacePrefix: true
+//	stripPrefixes: ["urn:oid:"]
+//	rename:
+//	  0.9.2342.19200300.100.1.3: mail
+//	aliases:
+//	  mail: [email, emailAddress]
+type AttributeNormalizer struct {
+	StripNamespacePrefix bool                `yaml:"stripNamespacePrefix"` // Remove an XML namespace prefix such as "cas:" from names
+	StripPrefixes        []string            `yaml:"stripPrefixes"`        // Remove the first matching prefix from names, e.g. "urn:oid:"
+	Rename               map[string]string   `yaml:"rename"`               // Exact old name to new name
+	Aliases              map[string][]string `yaml:"aliases"`              // Canonical name to alternative names, matched ignoring case
+	FoldCase             bool                `yaml:"foldCase"`             // Lower case names not matched by Rename or Aliases
+
+	once    sync.Once
+	aliases map[string]string // lower case alternative name -> canonical name
+}
+
+// LoadAttributeNormalizer creates an AttributeNormalizer from its YAML configuration
+func LoadAttributeNormalizer(data []byte) (*AttributeNormalizer, error) {
+	var n AttributeNormalizer
+	if err := yaml.UnmarshalStrict(data, &n); err != nil {
+		return nil, fmt.Errorf("cas: attribute normalizer: %v", err)
+	}
+
+	return &n, nil
+}
+
+// Name returns the normalised form of an attribute name
+func (n *AttributeNormalizer) Name(name string) string {
+	if n.StripNamespacePrefix {
+		name = stripNamespacePrefix(name)
+	}
+
+	for _, prefix := range n.StripPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			name = strings.TrimPrefix(name, prefix)
+			break
+		}
+	}
+
+	if renamed, ok := n.Rename[name]; ok {
+		return renamed
+	}
+
+	if canonical, ok := n.aliasIndex()[strings.ToLower(name)]; ok {
+		return canonical
+	}
+
+	if n.FoldCase {
+		return strings.ToLower(name)
+	}
+
+	return name
+}
+
+// stripNamespacePrefix removes a single XML namespace prefix such as "cas:".
+// Names qualified otherwise, like URNs and URLs, are returned unchanged.
+func stripNamespacePrefix(name string) string {
+	i := strings.IndexByte(name, ':')
+	if i <= 0 || !isNamespacePrefix(name[:i]) {
+		return name
+	}
+
+	local := name[i+1:]
+	if local == "" || strings.ContainsAny(local, ":/") {
+		return name
+	}
+
+	return local
+}
+
+// isNamespacePrefix reports whether p only consists of name characters
+func isNamespacePrefix(p string) bool {
+	for _, r := range p {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// aliasIndex returns the alternative names, lower cased, mapped to their canonical name
+func (n *AttributeNormalizer) aliasIndex() map[string]string {
+	n.once.Do(func() {
+		n.aliases = make(map[string]string)
+		for canonical, alternatives := range n.Aliases {
+			n.aliases[strings.ToLower(canonical)] = canonical
+			for _, alt := range alternatives {
+				n.aliases[strings.ToLower(alt)] = canonical
+			}
+		}
+	})
+
+	return n.aliases
+}
+
+// Normalize renames the attributes of the AuthenticationResponse in place.
+// Merged values are ordered by original attribute name, duplicates are dropped.
+func (n *AttributeNormalizer) Normalize(a *AuthenticationResponse) {
+	if n == nil || a == nil || len(a.Attributes) == 0 {
+		return
+	}
+
+	a.Attributes = n.normalize(a.Attributes)
+	for source, attributes := range a.AttributeSources {
+		a.AttributeSources[source] = n.normalize(attributes)
+	}
+}
+
+// normalize returns the attributes with normalized names
+func (n *AttributeNormalizer) normalize(a UserAttributes) UserAttributes {
+	attributes := make(UserAttributes, len(a))
+
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		normalized := n.Name(name)
+
+		for _, v := range a[name] {
+			if !containsAny(attributes[normalized], []string{v}) {
+				attributes.Add(normalized, v)
+			}
+		}
+	}
+
+	return attributes
+}
@@ -0,0 +1,125 @@
+package cas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+const testAttributeNormalizerConfig = `
+stripNamespacePrefix: true
+stripPrefixes: ["urn:oid:"]
+rename:
+  0.9.2342.19200300.100.1.3: mail
+aliases:
+  mail: [email, emailAddress]
+  displayName: [cn]
+foldCase: true
+`
+
+func TestAttributeNormalizer(t *testing.T) {
+	n, err := LoadAttributeNormalizer([]byte(testAttributeNormalizerConfig))
+	if err != nil {
+		t.Fatalf("LoadAttributeNormalizer failed: %v", err)
+	}
+
+	names := map[string]string{
+		"mail":                              "mail",
+		"Email":                             "mail",
+		"cas:EMAILADDRESS":                  "mail",
+		"urn:oid:0.9.2342.19200300.100.1.3": "mail",
+		"CN":                                "displayName",
+		"Department":                        "department",
+	}
+
+	for name, expected := range names {
+		if got := n.Name(name); got != expected {
+			t.Errorf("Name(%q) = %q, want %q", name, got, expected)
+		}
+	}
+
+	a := &AuthenticationResponse{
+		Attributes: UserAttributes{
+			"Email":          {"alice@example.com"},
+			"mail":           {"alice@example.com", "a@example.com"},
+			"cas:Department": {"ops"},
+		},
+		AttributeSources: map[AttributeSource]UserAttributes{
+			AttributeSourceRubyCAS:    {"Email": {"alice@example.com"}},
+			AttributeSourceAttributes: {"mail": {"alice@example.com", "a@example.com"}},
+			AttributeSourceNamed:      {"cas:Department": {"ops"}},
+		},
+	}
+
+	n.Normalize(a)
+
+	expected := UserAttributes{
+		"mail":       {"alice@example.com", "a@example.com"},
+		"department": {"ops"},
+	}
+
+	if !reflect.DeepEqual(a.Attributes, expected) {
+		t.Errorf("Expected %v, got %v", expected, a.Attributes)
+	}
+
+	if !a.AttributesFrom(AttributeSourceRubyCAS).Has("mail") || !a.AttributesFrom(AttributeSourceNamed).Has("department") {
+		t.Errorf("Unexpected sources %v", a.AttributeSources)
+	}
+
+	if _, err := LoadAttributeNormalizer([]byte("alias: {}")); err == nil {
+		t.Errorf("Expected unknown field to be rejected")
+	}
+}
+
+func TestStripNamespacePrefix(t *testing.T) {
+	n := &AttributeNormalizer{StripNamespacePrefix: true}
+
+	names := map[string]string{
+		"cas:mail":                          "mail",
+		"saml2-attr:mail":                   "mail",
+		"mail":                              "mail",
+		":mail":                             ":mail",
+		"cas:":                              "cas:",
+		"urn:oid:0.9.2342.19200300.100.1.3": "urn:oid:0.9.2342.19200300.100.1.3",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress": "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+		"example.com:mail": "example.com:mail",
+	}
+
+	for name, expected := range names {
+		if got := n.Name(name); got != expected {
+			t.Errorf("Name(%q) = %q, want %q", name, got, expected)
+		}
+	}
+}
+
+func TestValidateTicketNormalizesAttributes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+    <cas:user>alice</cas:user>
+    <cas:Email>alice@example.com</cas:Email>
+  </cas:authenticationSuccess>
+</cas:serviceResponse>`))
+	}))
+	defer server.Close()
+
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	serviceURL, _ := url.Parse("https://service.example.com/")
+	restClient := NewRestClient(&RestOptions{
+		CasURL:              casURL,
+		ServiceURL:          serviceURL,
+		Client:              server.Client(),
+		AttributeNormalizer: &AttributeNormalizer{Aliases: map[string][]string{"mail": {"email"}}},
+	})
+
+	success, err := restClient.ValidateServiceTicket("ST-1")
+	if err != nil {
+		t.Fatalf("ValidateServiceTicket failed: %v", err)
+	}
+
+	if v := success.Attributes.Get("mail"); v != "alice@example.com" {
+		t.Errorf("Expected normalised mail attribute, got %v", success.Attributes)
+	}
+}
@@ -0,0 +1,239 @@
+package cas
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrMissingAttribute is reported for a required attribute without values
+var ErrMissingAttribute = errors.New("cas: missing attribute")
+
+// AttributeError reports an attribute which could not be stored in a struct field
+type AttributeError struct {
+	Field     string // Name of the struct field
+	Attribute string // Name of the attribute, or @user / @memberOf
+	Err       error  // Reason the attribute could not be stored
+}
+
+// Error returns the AttributeError as a string
+func (e *AttributeError) Error() string {
+	return fmt.Sprintf("cas: attribute %s (field %s): %v", e.Attribute, e.Field, e.Err)
+}
+
+// Unwrap returns the reason the attribute could not be stored
+func (e *AttributeError) Unwrap() error {
+	return e.Err
+}
+
+// AttributeErrors lists every field UnmarshalAttributes was unable to set
+type AttributeErrors []*AttributeError
+
+// Error returns the AttributeErrors as a string
+func (e AttributeErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the AttributeErrors matches target, so
+// errors.Is(err, ErrMissingAttribute) holds if a required attribute is missing
+func (e AttributeErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UnmarshalAttributes stores the attributes of the AuthenticationResponse in
+// the struct pointed to by v.
+//
+// Fields are mapped with a cas struct tag naming the attribute, followed by
+// ",required" if unmarshalling should fail when the attribute has no values.
+// The names @user and @memberOf map the User and MemberOf of the response.
+// Fields without a cas tag, or tagged "-", are left alone.
+//
+//	type User struct {
+//		Login  string    `cas:"@user"`
+//		Mail   string    `cas:"mail,required"`
+//		Groups []string  `cas:"@memberOf"`
+//		Admin  bool      `cas:"isAdmin"`
+//		Uid    int       `cas:"uidNumber"`
+//		Expiry time.Time `cas:"passwordExpiry"`
+//	}
+//
+// Supported field types are string, bool, integers, time.Time, types
+// implementing encoding.TextUnmarshaler, pointers to these, and slices of
+// these. Scalar fields receive the first value of the attribute. Fields which
+// could not be set are reported together as AttributeErrors.
+func UnmarshalAttributes(a *AuthenticationResponse, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cas: UnmarshalAttributes requires a non-nil struct pointer, got %T", v)
+	}
+
+	if a == nil {
+		return errors.New("cas: UnmarshalAttributes requires an AuthenticationResponse")
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var errs AttributeErrors
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+
+		tag, ok := f.Tag.Lookup("cas")
+		if !ok || tag == "-" || f.PkgPath != "" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		required := opts == "required"
+
+		var values []string
+		switch name {
+		case "@user":
+			if a.User != "" {
+				values = []string{a.User}
+			}
+		case "@memberOf":
+			values = a.MemberOf
+		default:
+			values = a.Attributes[name]
+		}
+
+		if len(values) == 0 {
+			if required {
+				errs = append(errs, &AttributeError{Field: f.Name, Attribute: name, Err: ErrMissingAttribute})
+			}
+
+			continue
+		}
+
+		if err := setAttributeField(rv.Field(i), values); err != nil {
+			errs = append(errs, &AttributeError{Field: f.Name, Attribute: name, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setAttributeField stores the attribute values in the field
+func setAttributeField(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && !reflect.PtrTo(field.Type()).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setAttributeValue(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+
+		field.Set(s)
+		return nil
+	}
+
+	return setAttributeValue(field, values[0])
+}
+
+// setAttributeValue stores a single attribute value in v
+func setAttributeValue(v reflect.Value, value string) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setAttributeValue(p.Elem(), value); err != nil {
+			return err
+		}
+
+		v.Set(p)
+		return nil
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) && v.Type() != timeType {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	if v.Type() == timeType {
+		t, err := parseAttributeTime(value)
+		if err != nil {
+			return err
+		}
+
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	default:
+		return fmt.Errorf("unsupported field type %v", v.Type())
+	}
+
+	return nil
+}
+
+// attributeTimeLayouts are the time formats accepted for time attributes
+var attributeTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"20060102150405Z0700", // LDAP generalized time
+	"20060102150405.999999999Z0700",
+	"2006-01-02",
+}
+
+// parseAttributeTime parses the time formats commonly used in CAS attributes.
+// A zone id suffix such as [Europe/Berlin], as sent by Java based servers, is
+// ignored in favour of the offset.
+func parseAttributeTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if i := strings.IndexByte(value, '['); i > 0 && strings.HasSuffix(value, "]") {
+		value = value[:i]
+	}
+
+	for _, layout := range attributeTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("cas: unrecognised time %q", value)
+}
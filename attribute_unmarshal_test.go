@@ -0,0 +1,143 @@
+package cas
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testAttributeUser struct {
+	Login    string    `cas:"@user"`
+	Mail     string    `cas:"mail,required"`
+	Groups   []string  `cas:"@memberOf"`
+	Admin    bool      `cas:"isAdmin"`
+	Uid      int       `cas:"uidNumber"`
+	Quota    *uint64   `cas:"quota"`
+	Expiry   time.Time `cas:"passwordExpiry"`
+	Aliases  []string  `cas:"alias"`
+	Address  net.IP    `cas:"lastAddress"`
+	Missing  string    `cas:"missing"`
+	Ignored  string    `cas:"-"`
+	Untagged string
+}
+
+func TestUnmarshalAttributes(t *testing.T) {
+	a := &AuthenticationResponse{
+		User:     "alice",
+		MemberOf: []string{"staff", "admins"},
+		Attributes: UserAttributes{
+			"mail":           {"alice@example.com"},
+			"isAdmin":        {"true"},
+			"uidNumber":      {"1001"},
+			"quota":          {"1024"},
+			"passwordExpiry": {"2026-11-01T10:00:00.000+01:00[Europe/Paris]"},
+			"alias":          {"ali", "al"},
+			"lastAddress":    {"192.0.2.1"},
+			"-":              {"nope"},
+			"Untagged":       {"nope"},
+		},
+	}
+
+	var u testAttributeUser
+	if err := UnmarshalAttributes(a, &u); err != nil {
+		t.Fatalf("UnmarshalAttributes failed: %v", err)
+	}
+
+	quota := uint64(1024)
+	expected := testAttributeUser{
+		Login:   "alice",
+		Mail:    "alice@example.com",
+		Groups:  []string{"staff", "admins"},
+		Admin:   true,
+		Uid:     1001,
+		Quota:   &quota,
+		Expiry:  time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC),
+		Aliases: []string{"ali", "al"},
+		Address: net.ParseIP("192.0.2.1"),
+	}
+
+	if !u.Expiry.Equal(expected.Expiry) {
+		t.Errorf("Expected expiry %v, got %v", expected.Expiry, u.Expiry)
+	}
+	u.Expiry = expected.Expiry
+
+	if !reflect.DeepEqual(u, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, u)
+	}
+}
+
+func TestUnmarshalAttributesErrors(t *testing.T) {
+	a := &AuthenticationResponse{
+		User: "bob",
+		Attributes: UserAttributes{
+			"isAdmin":   {"maybe"},
+			"uidNumber": {"not a number"},
+		},
+	}
+
+	var u testAttributeUser
+	err := UnmarshalAttributes(a, &u)
+
+	var errs AttributeErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected AttributeErrors, got %v", err)
+	}
+
+	fields := make(map[string]error)
+	for _, e := range errs {
+		fields[e.Field] = e
+	}
+
+	if len(fields) != 3 || fields["Admin"] == nil || fields["Uid"] == nil {
+		t.Errorf("Expected errors for Mail, Admin and Uid, got %v", err)
+	}
+
+	if !errors.Is(fields["Mail"], ErrMissingAttribute) {
+		t.Errorf("Expected missing attribute error for Mail, got %v", fields["Mail"])
+	}
+
+	if !errors.Is(err, ErrMissingAttribute) {
+		t.Errorf("Expected returned error to match ErrMissingAttribute, got %v", err)
+	}
+
+	a.Attributes["mail"] = []string{"bob@example.com"}
+	if err := UnmarshalAttributes(a, &u); errors.Is(err, ErrMissingAttribute) {
+		t.Errorf("Expected no missing attribute once mail is set, got %v", err)
+	}
+
+	if u.Login != "bob" {
+		t.Errorf("Expected valid fields to be set despite errors, got %q", u.Login)
+	}
+
+	if err := UnmarshalAttributes(a, u); err == nil {
+		t.Errorf("Expected non-pointer to be rejected")
+	}
+}
+
+func TestParseAttributeTime(t *testing.T) {
+	expected := time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC)
+
+	for _, value := range []string{
+		"2026-10-19T08:30:00Z",
+		"2026-10-19T10:30:00+02:00",
+		"2026-10-19T10:30:00.000+02:00[Europe/Berlin]",
+		"2026-10-19 08:30:00 +0000",
+		"20261019083000Z",
+	} {
+		got, err := parseAttributeTime(value)
+		if err != nil {
+			t.Errorf("parseAttributeTime(%q) failed: %v", value, err)
+			continue
+		}
+
+		if !got.Equal(expected) {
+			t.Errorf("parseAttributeTime(%q) = %v, want %v", value, got, expected)
+		}
+	}
+
+	if _, err := parseAttributeTime("yesterday"); err == nil {
+		t.Errorf("Expected invalid time to be rejected")
+	}
+}
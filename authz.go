@@ -0,0 +1,150 @@
+package cas
+
+import (
+	"net/http"
+
+	"github.com/golang/glog"
+)
+
+// Rule decides whether an authenticated user is allowed access
+type Rule func(a *AuthenticationResponse) bool
+
+// Middleware wraps a http.Handler with additional behaviour
+type Middleware func(h http.Handler) http.Handler
+
+// Require returns Middleware which only passes requests to the wrapped handler
+// when the authenticated user satisfies rule.
+//
+// The middleware must be used within Client.Handle or Client.Handler.
+// Unauthenticated users are redirected to the CAS login page, users failing
+// the rule are handled by Forbidden.
+func Require(rule Rule) Middleware {
+	return requireRequest(func(_ *http.Request, a *AuthenticationResponse) bool {
+		return rule(a)
+	})
+}
+
+// requireRequest returns Middleware which only passes requests satisfying allow
+// to the wrapped handler.
+func requireRequest(allow func(r *http.Request, a *AuthenticationResponse) bool) Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			a := getAuthenticationResponse(r)
+			if a == nil {
+				RedirectToLogin(w, r)
+				return
+			}
+
+			if !allow(r, a) {
+				if glog.V(1) {
+					glog.Infof("cas: access to %v denied for %v", r.URL, a.User)
+				}
+
+				Forbidden(w, r)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireUser returns Middleware allowing access to the listed users
+func RequireUser(users ...string) Middleware {
+	return Require(User(users...))
+}
+
+// RequireGroup returns Middleware allowing access to members of any of the listed groups
+func RequireGroup(groups ...string) Middleware {
+	return Require(Group(groups...))
+}
+
+// RequireAnyAttribute returns Middleware allowing access to users with any of
+// the values for the named attribute. Without values any value is accepted.
+func RequireAnyAttribute(name string, values ...string) Middleware {
+	return Require(AnyAttribute(name, values...))
+}
+
+// User returns a Rule satisfied by any of the listed users
+func User(users ...string) Rule {
+	return func(a *AuthenticationResponse) bool {
+		return containsAny([]string{a.User}, users)
+	}
+}
+
+// Group returns a Rule satisfied by members of any of the listed groups
+func Group(groups ...string) Rule {
+	return func(a *AuthenticationResponse) bool {
+		return containsAny(a.MemberOf, groups)
+	}
+}
+
+// AnyAttribute returns a Rule satisfied by users with any of the values for
+// the named attribute. Without values any value is accepted.
+func AnyAttribute(name string, values ...string) Rule {
+	return func(a *AuthenticationResponse) bool {
+		v := a.Attributes[name]
+		if len(values) == 0 {
+			return len(v) > 0
+		}
+
+		return containsAny(v, values)
+	}
+}
+
+// All returns a Rule satisfied when every rule is satisfied
+func All(rules ...Rule) Rule {
+	return func(a *AuthenticationResponse) bool {
+		for _, rule := range rules {
+			if !rule(a) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Any returns a Rule satisfied when at least one rule is satisfied
+func Any(rules ...Rule) Rule {
+	return func(a *AuthenticationResponse) bool {
+		for _, rule := range rules {
+			if rule(a) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Not returns a Rule satisfied when rule is not
+func Not(rule Rule) Rule {
+	return func(a *AuthenticationResponse) bool {
+		return !rule(a)
+	}
+}
+
+// containsAny reports whether any of want is in have
+func containsAny(have []string, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if h == w {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Forbidden responds to a request denied by authorization checks, using the
+// ForbiddenHandler of the Client associated with the request if configured.
+func Forbidden(w http.ResponseWriter, r *http.Request) {
+	if c := getClient(r); c != nil && c.forbiddenHandler != nil {
+		c.forbiddenHandler.ServeHTTP(w, r)
+		return
+	}
+
+	http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+}
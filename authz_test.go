@@ -0,0 +1,79 @@
+package cas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRequire(t *testing.T) {
+	casURL, _ := url.Parse("https://cas.example.com/cas/")
+	client := NewClient(&Options{URL: casURL})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	alice := &AuthenticationResponse{
+		User:       "alice",
+		MemberOf:   []string{"staff", "admins"},
+		Attributes: UserAttributes{"department": {"engineering"}},
+	}
+
+	tests := map[string]struct {
+		middleware Middleware
+		user       *AuthenticationResponse
+		code       int
+	}{
+		"unauthenticated":       {RequireUser("alice"), nil, http.StatusFound},
+		"user":                  {RequireUser("bob", "alice"), alice, http.StatusOK},
+		"wrong user":            {RequireUser("bob"), alice, http.StatusForbidden},
+		"group":                 {RequireGroup("admins"), alice, http.StatusOK},
+		"wrong group":           {RequireGroup("students"), alice, http.StatusForbidden},
+		"attribute":             {RequireAnyAttribute("department", "sales", "engineering"), alice, http.StatusOK},
+		"attribute any value":   {RequireAnyAttribute("department"), alice, http.StatusOK},
+		"wrong attribute value": {RequireAnyAttribute("department", "sales"), alice, http.StatusForbidden},
+		"missing attribute":     {RequireAnyAttribute("office"), alice, http.StatusForbidden},
+		"all":                   {Require(All(Group("staff"), Not(User("bob")))), alice, http.StatusOK},
+		"any":                   {Require(Any(Group("students"), User("bob"))), alice, http.StatusForbidden},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "https://service.example.com/admin", nil)
+			setClient(r, client)
+			if tc.user != nil {
+				setAuthenticationResponse(r, tc.user)
+			}
+
+			w := httptest.NewRecorder()
+			tc.middleware(ok).ServeHTTP(w, r)
+
+			if w.Code != tc.code {
+				t.Errorf("Expected status %d, got %d", tc.code, w.Code)
+			}
+		})
+	}
+}
+
+func TestForbiddenHandler(t *testing.T) {
+	casURL, _ := url.Parse("https://cas.example.com/cas/")
+	client := NewClient(&Options{
+		URL: casURL,
+		ForbiddenHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "members only", http.StatusForbidden)
+		}),
+	})
+
+	r := httptest.NewRequest("GET", "https://service.example.com/admin", nil)
+	setClient(r, client)
+	setAuthenticationResponse(r, &AuthenticationResponse{User: "bob"})
+
+	w := httptest.NewRecorder()
+	RequireGroup("admins")(http.NotFoundHandler()).ServeHTTP(w, r)
+
+	if w.Code != http.StatusForbidden || w.Body.String() != "members only\n" {
+		t.Errorf("Expected custom forbidden response, got %d %q", w.Code, w.Body.String())
+	}
+}
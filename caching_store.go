@@ -0,0 +1,206 @@
+package cas
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultCacheTTL is the time entries are held in the local cache of a
+// CachingTicketStore or CachingSessionStore when no ttl is given.
+const DefaultCacheTTL = 30 * time.Second
+
+// CacheOptions : Local cache configuration options
+type CacheOptions struct {
+	TTL        time.Duration // Time entries are cached for, DefaultCacheTTL if zero
+	MaxEntries int           // Maximum number of cached entries, unlimited if zero
+	Clock      Clock         // Custom Clock, if nil the system time is used
+}
+
+// newLocalCacheFromOptions creates the local cache described by options.
+func newLocalCacheFromOptions(options *CacheOptions) *localCache {
+	if options == nil {
+		options = &CacheOptions{}
+	}
+
+	ttl := options.TTL
+	if ttl == 0 {
+		ttl = DefaultCacheTTL
+	}
+
+	return newLocalCache(options.Clock, ttl, options.MaxEntries)
+}
+
+// CachingTicketStore is a TicketStore which keeps a short lived local copy of
+// the AuthenticationResponse data held in a backing TicketStore.
+//
+// Repeat reads of a ticket within the ttl are answered locally. Entries are
+// evicted when deleted through the store, which includes CAS Single Logout
+// requests handled by this process. Other processes sharing the backing store
+// may serve a deleted ticket until their local entry expires, so the ttl should
+// be kept short.
+type CachingTicketStore struct {
+	backend ContextTicketStore
+	cache   *localCache
+}
+
+// NewCachingTicketStore creates a CachingTicketStore in front of backend. If
+// options is nil the defaults of CacheOptions are used.
+func NewCachingTicketStore(backend TicketStore, options *CacheOptions) *CachingTicketStore {
+	return &CachingTicketStore{
+		backend: NewContextTicketStore(backend),
+		cache:   newLocalCacheFromOptions(options),
+	}
+}
+
+// Read returns the AuthenticationResponse for a ticket
+func (s *CachingTicketStore) Read(id string) (*AuthenticationResponse, error) {
+	return s.ReadContext(context.Background(), id)
+}
+
+// ReadContext returns the AuthenticationResponse for a ticket, consulting the
+// backing store only when the ticket is not cached locally.
+func (s *CachingTicketStore) ReadContext(ctx context.Context, id string) (*AuthenticationResponse, error) {
+	if v, ok := s.cache.get(id); ok {
+		return v.(*AuthenticationResponse), nil
+	}
+
+	t, err := s.backend.ReadContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.set(id, t)
+	return t, nil
+}
+
+// Write stores the AuthenticationResponse for a ticket
+func (s *CachingTicketStore) Write(id string, ticket *AuthenticationResponse) error {
+	return s.WriteContext(context.Background(), id, ticket)
+}
+
+// WriteContext stores the AuthenticationResponse for a ticket in the backing
+// store and the local cache.
+func (s *CachingTicketStore) WriteContext(ctx context.Context, id string, ticket *AuthenticationResponse) error {
+	s.cache.delete(id)
+
+	if err := s.backend.WriteContext(ctx, id, ticket); err != nil {
+		return err
+	}
+
+	s.cache.set(id, ticket)
+	return nil
+}
+
+// Delete removes the AuthenticationResponse for a ticket
+func (s *CachingTicketStore) Delete(id string) error {
+	return s.DeleteContext(context.Background(), id)
+}
+
+// DeleteContext removes the AuthenticationResponse for a ticket from the local
+// cache and the backing store.
+func (s *CachingTicketStore) DeleteContext(ctx context.Context, id string) error {
+	s.cache.delete(id)
+	return s.backend.DeleteContext(ctx, id)
+}
+
+// Clear removes all ticket data
+func (s *CachingTicketStore) Clear() error {
+	return s.ClearContext(context.Background())
+}
+
+// ClearContext removes all ticket data from the local cache and the backing store.
+func (s *CachingTicketStore) ClearContext(ctx context.Context) error {
+	s.cache.purge()
+	return s.backend.ClearContext(ctx)
+}
+
+// Invalidate evicts a ticket from the local cache, leaving the backing store untouched.
+func (s *CachingTicketStore) Invalidate(id string) {
+	s.cache.delete(id)
+}
+
+// CachingSessionStore is a SessionStore which keeps a short lived local copy of
+// the session to ticket mappings held in a backing SessionStore.
+type CachingSessionStore struct {
+	backend ContextSessionStore
+	cache   *localCache
+}
+
+// NewCachingSessionStore creates a CachingSessionStore in front of backend. If
+// options is nil the defaults of CacheOptions are used.
+func NewCachingSessionStore(backend SessionStore, options *CacheOptions) *CachingSessionStore {
+	return &CachingSessionStore{
+		backend: NewContextSessionStore(backend),
+		cache:   newLocalCacheFromOptions(options),
+	}
+}
+
+// Get the ticket with the session id
+func (s *CachingSessionStore) Get(sessionID string) (string, bool) {
+	ticket, err := s.GetContext(context.Background(), sessionID)
+	return ticket, err == nil
+}
+
+// GetContext returns the ticket for the session id, consulting the backing
+// store only when the session is not cached locally.
+func (s *CachingSessionStore) GetContext(ctx context.Context, sessionID string) (string, error) {
+	if v, ok := s.cache.get(sessionID); ok {
+		return v.(string), nil
+	}
+
+	ticket, err := s.backend.GetContext(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	s.cache.set(sessionID, ticket)
+	return ticket, nil
+}
+
+// Set the session with a ticket
+func (s *CachingSessionStore) Set(sessionID, ticket string) error {
+	return s.SetContext(context.Background(), sessionID, ticket)
+}
+
+// SetContext associates the session with a ticket in the backing store and
+// the local cache.
+func (s *CachingSessionStore) SetContext(ctx context.Context, sessionID, ticket string) error {
+	s.cache.delete(sessionID)
+
+	if err := s.backend.SetContext(ctx, sessionID, ticket); err != nil {
+		return err
+	}
+
+	s.cache.set(sessionID, ticket)
+	return nil
+}
+
+// Delete the session
+func (s *CachingSessionStore) Delete(sessionID string) error {
+	return s.DeleteContext(context.Background(), sessionID)
+}
+
+// DeleteContext removes the session from the local cache and the backing store.
+//
+// Single Logout requests delete by service ticket rather than session id, so
+// any locally cached sessions mapped to a ticket equal to sessionID are
+// evicted as well.
+func (s *CachingSessionStore) DeleteContext(ctx context.Context, sessionID string) error {
+	s.cache.delete(sessionID)
+	s.InvalidateTicket(sessionID)
+
+	return s.backend.DeleteContext(ctx, sessionID)
+}
+
+// Invalidate evicts a session from the local cache, leaving the backing store untouched.
+func (s *CachingSessionStore) Invalidate(sessionID string) {
+	s.cache.delete(sessionID)
+}
+
+// InvalidateTicket evicts all sessions mapped to ticket from the local cache,
+// leaving the backing store untouched.
+func (s *CachingSessionStore) InvalidateTicket(ticket string) {
+	s.cache.deleteFunc(func(_ string, v interface{}) bool {
+		return v.(string) == ticket
+	})
+}
@@ -0,0 +1,225 @@
+package cas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingTicketStore records the number of reads reaching the backing store.
+type countingTicketStore struct {
+	MemoryStore
+	reads int
+}
+
+func (s *countingTicketStore) Read(id string) (*AuthenticationResponse, error) {
+	s.reads++
+	return s.MemoryStore.Read(id)
+}
+
+// countingSessionStore records the number of lookups reaching the backing store.
+type countingSessionStore struct {
+	SessionStore
+	gets int
+}
+
+func (s *countingSessionStore) Get(sessionID string) (string, bool) {
+	s.gets++
+	return s.SessionStore.Get(sessionID)
+}
+
+func TestCachingTicketStore(t *testing.T) {
+	backend := &countingTicketStore{}
+	store := NewCachingTicketStore(backend, nil)
+
+	user := &AuthenticationResponse{User: "user"}
+	if err := store.Write("ST-1", user); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if ar, err := store.Read("ST-1"); err != nil || ar != user {
+			t.Errorf("Expected cached response, got %v, %v", ar, err)
+		}
+	}
+
+	if backend.reads != 0 {
+		t.Errorf("Expected no reads of the backing store, got %d", backend.reads)
+	}
+
+	store.Invalidate("ST-1")
+	if _, err := store.Read("ST-1"); err != nil {
+		t.Errorf("Expected invalidated ticket to be read from the backing store, got %v", err)
+	}
+
+	if backend.reads != 1 {
+		t.Errorf("Expected 1 read of the backing store, got %d", backend.reads)
+	}
+
+	if err := store.Delete("ST-1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if _, err := store.Read("ST-1"); err != ErrInvalidTicket {
+		t.Errorf("Expected ErrInvalidTicket, got %v", err)
+	}
+
+	if backend.reads != 2 {
+		t.Errorf("Expected 2 reads of the backing store, got %d", backend.reads)
+	}
+}
+
+func TestCachingTicketStore_Expiry(t *testing.T) {
+	clock := newTestClock()
+	backend := &countingTicketStore{}
+	store := NewCachingTicketStore(backend, &CacheOptions{TTL: time.Minute, Clock: clock})
+
+	if err := store.Write("ST-1", &AuthenticationResponse{User: "user"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if _, err := store.Read("ST-1"); err != nil || backend.reads != 0 {
+		t.Errorf("Expected ticket to be cached, got %v after %d reads", err, backend.reads)
+	}
+
+	clock.Advance(time.Minute)
+
+	if _, err := store.Read("ST-1"); err != nil || backend.reads != 1 {
+		t.Errorf("Expected expired ticket to be read from the backing store, got %v after %d reads", err, backend.reads)
+	}
+}
+
+func TestCachingTicketStore_MaxEntries(t *testing.T) {
+	store := NewCachingTicketStore(&MemoryStore{}, &CacheOptions{MaxEntries: 2})
+
+	for _, id := range []string{"ST-1", "ST-2", "ST-3"} {
+		if err := store.Write(id, &AuthenticationResponse{User: id}); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	if n := len(store.cache.entries); n != 2 {
+		t.Errorf("Expected 2 cached entries, got %d", n)
+	}
+}
+
+func TestCachingSessionStore(t *testing.T) {
+	backend := &countingSessionStore{SessionStore: NewMemorySessionStore()}
+	ss := NewCachingSessionStore(backend, nil)
+
+	if err := ss.Set("key1", "ST-1"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if err := ss.Set("key2", "ST-1"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if v, ok := ss.Get("key1"); !ok || v != "ST-1" {
+			t.Errorf("Expected key1 to map to ST-1, got %q, %v", v, ok)
+		}
+	}
+
+	if backend.gets != 0 {
+		t.Errorf("Expected no lookups in the backing store, got %d", backend.gets)
+	}
+
+	// Single logout deletes by ticket
+	if err := ss.Delete("ST-1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if v, ok := ss.Get("key2"); !ok || v != "ST-1" {
+		t.Errorf("Expected key2 to map to ST-1, got %q, %v", v, ok)
+	}
+
+	if backend.gets != 1 {
+		t.Errorf("Expected 1 lookup in the backing store, got %d", backend.gets)
+	}
+
+	if err := ss.Delete("key2"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if _, ok := ss.Get("key2"); ok {
+		t.Errorf("Expected key2 to be deleted")
+	}
+}
+
+func TestCachingStoreSingleLogOut(t *testing.T) {
+	server, ticket := newTestServerWithTicket()
+	defer server.Close()
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	backend := &countingTicketStore{}
+	u, _ := url.Parse(ts.URL)
+	client := NewClient(&Options{
+		URL:   u,
+		Store: NewCachingTicketStore(backend, nil),
+	})
+
+	handler := client.HandleFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAuthenticated(r) {
+			RedirectToLogin(w, r)
+			return
+		}
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com/?ticket="+ticket.Name, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 validating the ticket, got %d", w.Code)
+	}
+
+	cookies := (&http.Response{Header: w.Header()}).Cookies()
+	for i := 0; i < 3; i++ {
+		req, _ = http.NewRequest("GET", "http://example.com/", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 with session cookie, got %d", w.Code)
+		}
+	}
+
+	if backend.reads != 0 {
+		t.Errorf("Expected no reads of the backing store, got %d", backend.reads)
+	}
+
+	logoutRequest, err := xmlLogoutRequest(ticket.Name, client.clock, client.rand)
+	if err != nil {
+		t.Fatalf("xmlLogoutRequest failed: %v", err)
+	}
+
+	postData := make(url.Values)
+	postData.Set("logoutRequest", string(logoutRequest))
+
+	req, _ = http.NewRequest("POST", "http://example.com/", strings.NewReader(postData.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for the logout request, got %d", w.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "http://example.com/", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Errorf("Expected logged out session to be redirected, got %d", w.Code)
+	}
+}
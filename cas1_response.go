@@ -0,0 +1,38 @@
+package cas
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// parseCas1Response parses the line based response of the cas 1 validate endpoint.
+//
+// A successful response is "yes\n<user>\n", a rejected ticket is answered with
+// "no\n\n". Lines may end with CRLF and anything after the user is ignored.
+func parseCas1Response(body string) (*AuthenticationResponse, error) {
+	lines := strings.SplitN(body, "\n", 3)
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+
+	switch lines[0] {
+	case "yes":
+		if len(lines) < 2 || strings.TrimSpace(lines[1]) == "" {
+			return nil, malformedCas1Response(errors.New("cas: authentication success without user"))
+		}
+
+		return &AuthenticationResponse{
+			User:       strings.TrimSpace(lines[1]),
+			Attributes: make(UserAttributes),
+		}, nil
+	case "no":
+		return nil, &AuthenticationError{Code: INVALID_TICKET, Message: "ticket rejected by cas server"}
+	}
+
+	return nil, malformedCas1Response(fmt.Errorf("cas: unexpected first line %q", truncate(lines[0], 32)))
+}
+
+func malformedCas1Response(err error) error {
+	return &MalformedResponseError{Response: "validate response", Err: err}
+}
@@ -0,0 +1,110 @@
+package cas
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestParseCas1Response(t *testing.T) {
+	tests := map[string]struct {
+		body string
+		user string
+		err  error
+	}{
+		"success":           {body: "yes\nbob\n", user: "bob"},
+		"crlf":              {body: "yes\r\nbob\r\n", user: "bob"},
+		"no trailing":       {body: "yes\nbob", user: "bob"},
+		"trailing data":     {body: "yes\nbob\nextra\nlines\n", user: "bob"},
+		"rejected":          {body: "no\n\n", err: ErrTicketRejected},
+		"rejected crlf":     {body: "no\r\n\r\n", err: ErrTicketRejected},
+		"empty":             {body: "", err: ErrMalformedResponse},
+		"short":             {body: "ye", err: ErrMalformedResponse},
+		"missing user":      {body: "yes\n", err: ErrMalformedResponse},
+		"blank user":        {body: "yes\n  \n", err: ErrMalformedResponse},
+		"html error page":   {body: "<html><body>error</body></html>", err: ErrMalformedResponse},
+		"capitalised reply": {body: "YES\nbob\n", err: ErrMalformedResponse},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			success, err := parseCas1Response(tc.body)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Errorf("Expected error matching <%v>, got <%v>", tc.err, err)
+				}
+
+				if success != nil {
+					t.Errorf("Expected no response, got %#v", success)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if success.User != tc.user {
+				t.Errorf("Expected user %q, got %q", tc.user, success.User)
+			}
+		})
+	}
+}
+
+func FuzzParseCas1Response(f *testing.F) {
+	f.Add("yes\nbob\n")
+	f.Add("no\n\n")
+	f.Add("yes\r\nbob\r\n")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, body string) {
+		success, err := parseCas1Response(body)
+		if (success == nil) == (err == nil) {
+			t.Fatalf("Expected either a response or an error, got %#v, %v", success, err)
+		}
+
+		if success != nil && success.User == "" {
+			t.Fatalf("Expected user in successful response for %q", body)
+		}
+	})
+}
+
+func TestValidateTicketCas1UsesURLScheme(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/custom/validate":
+			w.Write([]byte("yes\r\nbob\r\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	scheme := NewDefaultURLScheme(casURL)
+	scheme.ValidatePath = "../custom/validate"
+	scheme.ServiceValidatePath = "../custom/serviceValidate"
+
+	client := NewClient(&Options{
+		URL:       casURL,
+		URLScheme: scheme,
+		Client:    server.Client(),
+	})
+
+	r := httptest.NewRequest("GET", "https://service.example.com/?ticket=ST-1", nil)
+	if err := client.validateTicket("ST-1", r); err != nil {
+		t.Fatalf("Expected validation using the custom validate path to succeed, got %v", err)
+	}
+
+	success, err := client.tickets.Read("ST-1")
+	if err != nil {
+		t.Fatalf("Expected ticket to be stored, got %v", err)
+	}
+
+	if success.User != "bob" {
+		t.Errorf("Expected user bob, got %q", success.User)
+	}
+}
@@ -59,6 +59,21 @@ func (ts *TestServer) AddTicket(ticket *TestTicket) {
 	ts.serviceTickets[ticket.Name] = ticket
 }
 
+// testTicketName is the service ticket issued by newTestServerWithTicket
+const testTicketName = "ST-l8d6b51d8e9c4569345a30e2f904626a1066384db8694784a60b515d62f6c"
+
+// newTestServerWithTicket returns a TestServer which validates testTicketName
+// for enoch.root at http://example.com/
+func newTestServerWithTicket() (*TestServer, *TestTicket) {
+	server := &TestServer{}
+	ticket := server.NewTicket(testTicketName)
+	ticket.Service = "http://example.com/"
+	ticket.Username = "enoch.root"
+	server.AddTicket(ticket)
+
+	return server, ticket
+}
+
 func (ts *TestServer) Close() {
 	ts.serviceTickets = nil
 }
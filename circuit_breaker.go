@@ -0,0 +1,213 @@
+package cas
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Circuit breaker defaults
+const (
+	DefaultBreakerFailureThreshold = 5
+	DefaultBreakerOpenTimeout      = 30 * time.Second
+)
+
+// ErrCircuitOpen is matched by errors.Is for the CircuitOpenError returned
+// while a CircuitBreaker is rejecting calls.
+var ErrCircuitOpen = errors.New("cas: circuit breaker open")
+
+// CircuitOpenError is returned instead of calling the CAS server while the
+// CircuitBreaker is open.
+type CircuitOpenError struct {
+	RetryAt time.Time // Time at which the breaker will allow a trial call
+}
+
+// Error returns the CircuitOpenError as a string
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%v until %v", ErrCircuitOpen, e.RetryAt.Format(time.RFC3339))
+}
+
+// Is reports whether target is ErrCircuitOpen
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState int
+
+// BreakerState values
+const (
+	BreakerClosed   BreakerState = iota // Calls are allowed
+	BreakerOpen                         // Calls are rejected
+	BreakerHalfOpen                     // A single trial call is allowed
+)
+
+// String returns the name of the BreakerState
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return ""
+	}
+}
+
+// BreakerOptions : CircuitBreaker configuration options
+type BreakerOptions struct {
+	FailureThreshold int                         // Consecutive failures which open the breaker, DefaultBreakerFailureThreshold if zero
+	OpenTimeout      time.Duration               // Time the breaker stays open before a trial call, DefaultBreakerOpenTimeout if zero
+	Clock            Clock                       // Custom Clock, if nil the system time is used
+	OnStateChange    func(from, to BreakerState) // Called on every state transition, e.g. to update metrics
+}
+
+// BreakerStats is a snapshot of the state and counters of a CircuitBreaker
+type BreakerStats struct {
+	State               BreakerState
+	ConsecutiveFailures int
+	Successes           uint64
+	Failures            uint64
+	Rejections          uint64
+	OpenedAt            time.Time // Zero unless the breaker is open or half-open
+}
+
+// CircuitBreaker stops calls to the CAS server once it is clearly unavailable,
+// failing fast with a CircuitOpenError until OpenTimeout has passed.
+//
+// A CircuitBreaker is safe for concurrent use and may be shared by several
+// Clients to give a single view of the CAS server health.
+type CircuitBreaker struct {
+	threshold     int
+	openTimeout   time.Duration
+	clock         Clock
+	onStateChange func(from, to BreakerState)
+
+	mu      sync.Mutex
+	stats   BreakerStats
+	probing time.Time // Start of the outstanding trial call, if any
+}
+
+// NewCircuitBreaker creates a CircuitBreaker with the provided options. If
+// options is nil the defaults are used.
+func NewCircuitBreaker(options *BreakerOptions) *CircuitBreaker {
+	if options == nil {
+		options = &BreakerOptions{}
+	}
+
+	b := &CircuitBreaker{
+		threshold:     options.FailureThreshold,
+		openTimeout:   options.OpenTimeout,
+		clock:         clockOrDefault(options.Clock),
+		onStateChange: options.OnStateChange,
+	}
+
+	if b.threshold <= 0 {
+		b.threshold = DefaultBreakerFailureThreshold
+	}
+
+	if b.openTimeout <= 0 {
+		b.openTimeout = DefaultBreakerOpenTimeout
+	}
+
+	return b
+}
+
+// State returns the current BreakerState
+func (b *CircuitBreaker) State() BreakerState {
+	return b.Stats().State
+}
+
+// Stats returns the current state and counters of the breaker
+func (b *CircuitBreaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	return b.stats
+}
+
+// Allow reports whether a call may proceed, returning a CircuitOpenError if not.
+// Every allowed call should be followed by Success or Failure. A trial call
+// which never reports back is abandoned after OpenTimeout.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+
+	switch b.stats.State {
+	case BreakerOpen:
+		b.stats.Rejections++
+		return &CircuitOpenError{RetryAt: b.stats.OpenedAt.Add(b.openTimeout)}
+	case BreakerHalfOpen:
+		now := b.clock.Now()
+		if !b.probing.IsZero() && now.Sub(b.probing) < b.openTimeout {
+			b.stats.Rejections++
+			return &CircuitOpenError{RetryAt: b.probing.Add(b.openTimeout)}
+		}
+
+		b.probing = now
+	}
+
+	return nil
+}
+
+// Success records a call which reached the CAS server
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = time.Time{}
+	b.stats.Successes++
+	b.stats.ConsecutiveFailures = 0
+	b.stats.OpenedAt = time.Time{}
+	b.transition(BreakerClosed)
+}
+
+// Failure records a call which failed because the CAS server was unavailable
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+
+	b.stats.Failures++
+	b.stats.ConsecutiveFailures++
+
+	if b.stats.State == BreakerHalfOpen || b.stats.ConsecutiveFailures >= b.threshold {
+		b.probing = time.Time{}
+		b.stats.OpenedAt = b.clock.Now()
+		b.transition(BreakerOpen)
+	}
+}
+
+// refresh moves an open breaker to half-open once the open timeout has passed.
+// Must be called with b.mu held.
+func (b *CircuitBreaker) refresh() {
+	if b.stats.State != BreakerOpen {
+		return
+	}
+
+	if b.clock.Now().Sub(b.stats.OpenedAt) >= b.openTimeout {
+		b.transition(BreakerHalfOpen)
+	}
+}
+
+// transition changes the breaker state, notifying OnStateChange.
+// Must be called with b.mu held.
+func (b *CircuitBreaker) transition(to BreakerState) {
+	from := b.stats.State
+	if from == to {
+		return
+	}
+
+	b.stats.State = to
+
+	if b.onStateChange != nil {
+		b.onStateChange(from, to)
+	}
+}
@@ -0,0 +1,114 @@
+package cas
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := newTestClock()
+
+	var transitions []string
+	b := NewCircuitBreaker(&BreakerOptions{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		Clock:            clock,
+		OnStateChange: func(from, to BreakerState) {
+			transitions = append(transitions, from.String()+">"+to.String())
+		},
+	})
+
+	if state := b.State(); state != BreakerClosed {
+		t.Errorf("Expected closed breaker, got %v", state)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Expected closed breaker to allow calls, got %v", err)
+		}
+
+		b.Failure()
+	}
+
+	if state := b.State(); state != BreakerOpen {
+		t.Errorf("Expected breaker to open after 2 failures, got %v", state)
+	}
+
+	err := b.Allow()
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || !openErr.RetryAt.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("Expected CircuitOpenError retrying after a minute, got %v", err)
+	}
+
+	clock.Advance(time.Minute)
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Errorf("Expected half-open breaker, got %v", state)
+	}
+
+	// only a single trial call is allowed
+	if err := b.Allow(); err != nil {
+		t.Errorf("Expected trial call to be allowed, got %v", err)
+	}
+
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected second trial call to be rejected, got %v", err)
+	}
+
+	b.Failure()
+	if state := b.State(); state != BreakerOpen {
+		t.Errorf("Expected failed trial to open the breaker, got %v", state)
+	}
+
+	clock.Advance(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Errorf("Expected trial call to be allowed, got %v", err)
+	}
+
+	b.Success()
+	if state := b.State(); state != BreakerClosed {
+		t.Errorf("Expected successful trial to close the breaker, got %v", state)
+	}
+
+	if stats := b.Stats(); stats.Successes != 1 || stats.Failures != 3 || stats.Rejections != 2 || stats.ConsecutiveFailures != 0 {
+		t.Errorf("Expected 1 success, 3 failures and 2 rejections, got %+v", stats)
+	}
+
+	expectedTransitions := []string{
+		"closed>open",
+		"open>half-open",
+		"half-open>open",
+		"open>half-open",
+		"half-open>closed",
+	}
+
+	if !reflect.DeepEqual(transitions, expectedTransitions) {
+		t.Errorf("Expected transitions %v, got %v", expectedTransitions, transitions)
+	}
+}
+
+func TestCircuitBreakerAbandonedTrial(t *testing.T) {
+	clock := newTestClock()
+	b := NewCircuitBreaker(&BreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute, Clock: clock})
+
+	b.Failure()
+	clock.Advance(time.Minute)
+
+	if err := b.Allow(); err != nil {
+		t.Errorf("Expected trial call to be allowed, got %v", err)
+	}
+
+	if err := b.Allow(); err == nil {
+		t.Errorf("Expected second trial call to be rejected")
+	}
+
+	clock.Advance(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Errorf("Expected abandoned trial to be retried, got %v", err)
+	}
+}
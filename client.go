@@ -1,14 +1,25 @@
 package cas
 
 import (
-	"crypto/rand"
+	"context"
+	"crypto"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/golang/glog"
 )
 
+// DefaultTicketReuseWindow is the time after validation during which a repeated
+// request with the same ticket is answered from the TicketStore.
+const DefaultTicketReuseWindow = 10 * time.Second
+
+// sharedValidationTimeout bounds a ticket validation shared by concurrent
+// requests, which is detached from the cancellation of the requests.
+const sharedValidationTimeout = time.Minute
+
 // Options : Client configuration options
 type Options struct {
 	URL          *url.URL     // URL to the CAS service
@@ -18,19 +29,76 @@ type Options struct {
 	URLScheme    URLScheme    // Custom url scheme, can be used to modify the request urls for the client
 	Cookie       *http.Cookie // http.Cookie options, uses Path, Domain, MaxAge, HttpOnly, & Secure
 	SessionStore SessionStore
+	Clock        Clock     // Custom Clock, if nil the system time is used
+	Rand         io.Reader // Custom source of randomness for identifiers and retry jitter, if nil crypto/rand is used
+
+	Retry          *RetryPolicy    // Retry policy for ticket validation, if nil validation is not retried
+	CircuitBreaker *CircuitBreaker // Circuit breaker for ticket validation, if nil none is used
+
+	// ValidationURLs lists the cas servers used for ticket validation, if empty URL is used.
+	// Browser redirects always go to URL.
+	ValidationURLs     []*url.URL
+	ValidationStrategy EndpointStrategy // How validation requests are spread over ValidationURLs
+
+	MaxResponseSize       int64 // Largest validation response accepted, DefaultMaxResponseSize if zero
+	StrictResponseParsing bool  // Reject validation responses with unknown structure
+
+	RequestMutator    RequestMutator    // Optional hook modifying requests to the cas server
+	ResponseInspector ResponseInspector // Optional hook observing responses from the cas server
+
+	AttributeNormalizer *AttributeNormalizer // Optional normalisation of attribute names after validation
+	PrivateKey          crypto.Decrypter     // Private key of the service, decrypts the credential and proxy granting ticket attributes
+
+	// TicketReuseWindow is the time after validation during which a repeated request for the same
+	// ticket and service is answered from the TicketStore, DefaultTicketReuseWindow if zero.
+	// A negative value disables reuse, concurrent validations are coalesced regardless.
+	TicketReuseWindow time.Duration
+
+	ForbiddenHandler http.Handler // Handles requests denied by authorization Middleware, if nil a plain 403 is sent
+	RoleMapper       *RoleMapper  // Maps groups and attributes to application roles, see Roles
+
+	// Routes set how Handler protects requests by path, the first matching route applies.
+	// Requests not matching any route require authentication.
+	Routes          []Route
+	LogoutPath      string   // Path logging the user out of CAS, DefaultLogoutPath if empty
+	LocalLogoutPath string   // Path ending the session with the service only, disabled if empty
+	BypassMethods   []string // Request methods passed on without authentication, e.g. OPTIONS for CORS preflight
+
+	// PrincipalResolver resolves the application principal of authenticated users once per session, see Principal.
+	PrincipalResolver     PrincipalResolver
+	PrincipalErrorHandler PrincipalErrorHandler // Responds when the principal can not be resolved, DefaultPrincipalErrorHandler if nil
+	PrincipalCacheSize    int                   // Most principals cached at once, DefaultPrincipalCacheSize if zero
 }
 
 // Client implements the main protocol
 type Client struct {
-	tickets   TicketStore
+	tickets   ContextTicketStore
 	client    *http.Client
 	urlScheme URLScheme
 	cookie    *http.Cookie
 
-	sessions    SessionStore
+	sessions    ContextSessionStore
 	sendService bool
 
+	clock Clock
+	rand  io.Reader
+
 	stValidator *ServiceTicketValidator
+	privateKey  crypto.Decrypter
+	validations flightGroup
+	validated   *localCache // recently validated ticket -> service url and ticket
+
+	forbiddenHandler http.Handler
+	roleMapper       *RoleMapper
+
+	routes          []Route
+	logoutPath      string
+	localLogoutPath string
+	bypassMethods   []string
+
+	principalResolver     PrincipalResolver
+	principalErrorHandler PrincipalErrorHandler
+	principals            *localCache // ticket -> resolved principal
 }
 
 // NewClient creates a Client with the provided Options.
@@ -39,18 +107,20 @@ func NewClient(options *Options) *Client {
 		glog.Infof("cas: new client with options %v", options)
 	}
 
+	clock := clockOrDefault(options.Clock)
+
 	var tickets TicketStore
 	if options.Store != nil {
 		tickets = options.Store
 	} else {
-		tickets = &MemoryStore{}
+		tickets = &MemoryStore{Clock: clock}
 	}
 
 	var sessions SessionStore
 	if options.SessionStore != nil {
 		sessions = options.SessionStore
 	} else {
-		sessions = NewMemorySessionStore()
+		sessions = newMemorySessionStore(clock)
 	}
 
 	var urlScheme URLScheme
@@ -79,17 +149,81 @@ func NewClient(options *Options) *Client {
 		}
 	}
 
+	reuseWindow := options.TicketReuseWindow
+	if reuseWindow == 0 {
+		reuseWindow = DefaultTicketReuseWindow
+	}
+
+	principalErrorHandler := options.PrincipalErrorHandler
+	if principalErrorHandler == nil {
+		principalErrorHandler = DefaultPrincipalErrorHandler
+	}
+
+	principalTTL := time.Duration(cookie.MaxAge) * time.Second
+	if principalTTL <= 0 {
+		principalTTL = DefaultPrincipalCacheTTL
+	}
+
+	principalCacheSize := options.PrincipalCacheSize
+	if principalCacheSize <= 0 {
+		principalCacheSize = DefaultPrincipalCacheSize
+	}
+
+	logoutPath := options.LogoutPath
+	if logoutPath == "" {
+		logoutPath = DefaultLogoutPath
+	}
+
+	stValidator := NewServiceTicketValidator(client, options.URL)
+	stValidator.endpoints = validationEndpoints(options.ValidationStrategy, clock, urlScheme, options.ValidationURLs)
+	stValidator.clock = clock
+	stValidator.rand = randOrDefault(options.Rand)
+	stValidator.Retry = options.Retry
+	stValidator.CircuitBreaker = options.CircuitBreaker
+	stValidator.MaxResponseSize = options.MaxResponseSize
+	stValidator.Strict = options.StrictResponseParsing
+	stValidator.RequestMutator = options.RequestMutator
+	stValidator.ResponseInspector = options.ResponseInspector
+	stValidator.AttributeNormalizer = options.AttributeNormalizer
+
 	return &Client{
-		tickets:     tickets,
+		tickets:     NewContextTicketStore(tickets),
 		client:      client,
 		urlScheme:   urlScheme,
 		cookie:      cookie,
-		sessions:    sessions,
+		sessions:    NewContextSessionStore(sessions),
 		sendService: options.SendService,
-		stValidator: NewServiceTicketValidator(client, options.URL),
+		stValidator: stValidator,
+		privateKey:  options.PrivateKey,
+		validations: flightGroup{timeout: sharedValidationTimeout},
+		validated:   newLocalCache(clock, reuseWindow, 0),
+		clock:       clock,
+		rand:        randOrDefault(options.Rand),
+
+		forbiddenHandler: options.ForbiddenHandler,
+		roleMapper:       options.RoleMapper,
+
+		routes:          options.Routes,
+		logoutPath:      logoutPath,
+		localLogoutPath: options.LocalLogoutPath,
+		bypassMethods:   options.BypassMethods,
+
+		principalResolver:     options.PrincipalResolver,
+		principalErrorHandler: principalErrorHandler,
+		principals:            newLocalCache(clock, principalTTL, principalCacheSize),
 	}
 }
 
+// validationEndpoints creates the endpointPool for ticket validation, falling
+// back to the url scheme if no validation urls are configured.
+func validationEndpoints(strategy EndpointStrategy, clock Clock, scheme URLScheme, validationURLs []*url.URL) *endpointPool {
+	if len(validationURLs) == 0 {
+		return newEndpointPool(strategy, clock, scheme)
+	}
+
+	return newURLEndpointPool(strategy, clock, validationURLs...)
+}
+
 // Handle wraps a http.Handler to provide CAS authentication for the handler.
 func (c *Client) Handle(h http.Handler) http.Handler {
 	return &clientHandler{
@@ -216,39 +350,82 @@ func (c *Client) RedirectToLogin(w http.ResponseWriter, r *http.Request) {
 }
 
 // validateTicket performs CAS ticket validation with the given ticket and service.
+//
+// Concurrent validations of the same ticket for the same service are coalesced
+// into a single request to the CAS server, as tickets may only be validated
+// once. The shared request outlives a cancelled caller, each caller stops
+// waiting when its own request is done. A ticket validated within the reuse
+// window is answered from the TicketStore.
 func (c *Client) validateTicket(ticket string, service *http.Request) error {
+	ctx := service.Context()
+
 	serviceURL, err := requestURL(service)
 	if err != nil {
 		return err
 	}
 
-	success, err := c.stValidator.ValidateTicket(serviceURL, ticket)
-	if err != nil {
-		return err
+	key := sanitisedURLString(serviceURL) + " " + ticket
+
+	if v, ok := c.validated.get(ticket); ok && v.(string) == key {
+		if _, err := c.tickets.ReadContext(ctx, ticket); err == nil {
+			if glog.V(2) {
+				glog.Infof("Ticket %v recently validated, re-using %T entry", ticket, c.tickets)
+			}
+
+			return nil
+		}
 	}
 
-	if err := c.tickets.Write(ticket, success); err != nil {
-		return err
+	shared, err := c.validations.do(ctx, key, func(ctx context.Context) error {
+		success, err := c.stValidator.ValidateTicketContext(ctx, serviceURL, ticket)
+		if err != nil {
+			return err
+		}
+
+		if success == nil {
+			return ErrInvalidTicket
+		}
+
+		if err := c.tickets.WriteContext(ctx, ticket, success); err != nil {
+			return err
+		}
+
+		c.validated.set(ticket, key)
+		return nil
+	})
+
+	if shared {
+		if glog.V(2) {
+			glog.Infof("Ticket %v validated by concurrent request: %v", ticket, err)
+		}
 	}
 
-	return nil
+	return err
 }
 
-// getSession finds or creates a session for the request.
+// getSession finds or creates a session for the request, returning the ticket
+// of an authenticated session.
 //
 // A cookie is set on the response if one is not provided with the request.
 // Validates the ticket if the URL parameter is provided.
-func (c *Client) getSession(w http.ResponseWriter, r *http.Request) {
-	cookie := c.getCookie(w, r)
+func (c *Client) getSession(w http.ResponseWriter, r *http.Request) string {
+	ctx := r.Context()
+	cookie, err := c.getCookie(w, r)
+	if err != nil {
+		if glog.V(1) {
+			glog.Infof("Error creating session cookie: %v", err)
+		}
+		return ""
+	}
 
-	if s, ok := c.sessions.Get(cookie.Value); ok {
-		if t, err := c.tickets.Read(s); err == nil {
+	if s, err := c.sessions.GetContext(ctx, cookie.Value); err == nil {
+		if t, err := c.tickets.ReadContext(ctx, s); err == nil {
 			if glog.V(1) {
 				glog.Infof("Re-used ticket %s for %s", s, t.User)
 			}
 
 			setAuthenticationResponse(r, t)
-			return
+			return s
 		} else {
 			if glog.V(2) {
 				glog.Infof("Ticket %v not in %T: %v", s, c.tickets, err)
@@ -260,6 +437,10 @@ func (c *Client) getSession(w http.ResponseWriter, r *http.Request) {
 
 			clearCookie(w, cookie)
 		}
+	} else if err != ErrInvalidSession {
+		if glog.V(2) {
+			glog.Infof("Session %v not in %T: %v", cookie.Value, c.sessions, err)
+		}
 	}
 
 	if ticket := r.URL.Query().Get("ticket"); ticket != "" {
@@ -267,18 +448,22 @@ func (c *Client) getSession(w http.ResponseWriter, r *http.Request) {
 			if glog.V(2) {
 				glog.Infof("Error validating ticket: %v", err)
 			}
-			return // allow ServeHTTP()
+			return "" // allow ServeHTTP()
 		}
 
-		c.setSession(cookie.Value, ticket)
+		if err := c.setSession(ctx, cookie.Value, ticket); err != nil {
+			if glog.V(2) {
+				glog.Infof("Error recording session: %v", err)
+			}
+		}
 
-		if t, err := c.tickets.Read(ticket); err == nil {
+		if t, err := c.tickets.ReadContext(ctx, ticket); err == nil {
 			if glog.V(1) {
 				glog.Infof("Validated ticket %s for %s", ticket, t.User)
 			}
 
 			setAuthenticationResponse(r, t)
-			return
+			return ticket
 		} else {
 			if glog.V(2) {
 				glog.Infof("Ticket %v not in %T: %v", ticket, c.tickets, err)
@@ -291,17 +476,24 @@ func (c *Client) getSession(w http.ResponseWriter, r *http.Request) {
 			clearCookie(w, cookie)
 		}
 	}
+
+	return ""
 }
 
 // getCookie finds or creates the session cookie on the response.
-func (c *Client) getCookie(w http.ResponseWriter, r *http.Request) *http.Cookie {
+func (c *Client) getCookie(w http.ResponseWriter, r *http.Request) (*http.Cookie, error) {
 	cookie, err := r.Cookie(sessionCookieName)
 	if err != nil {
+		id, err := newSessionID(c.rand)
+		if err != nil {
+			return nil, err
+		}
+
 		// NOTE: Intentionally not enabling HttpOnly so the cookie can
 		//       still be used by Ajax requests.
 		cookie = &http.Cookie{
 			Name:     sessionCookieName,
-			Value:    newSessionID(),
+			Value:    id,
 			Path:     c.cookie.Path,
 			Domain:   c.cookie.Domain,
 			MaxAge:   c.cookie.MaxAge,
@@ -318,22 +510,24 @@ func (c *Client) getCookie(w http.ResponseWriter, r *http.Request) *http.Cookie
 		http.SetCookie(w, cookie)
 	}
 
-	return cookie
+	return cookie, nil
 }
 
 // newSessionId generates a new opaque session identifier for use in the cookie.
-func newSessionID() string {
+func newSessionID(entropy io.Reader) (string, error) {
 	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
 	// generate 64 character string
 	bytes := make([]byte, 64)
-	rand.Read(bytes)
+	if _, err := io.ReadFull(entropy, bytes); err != nil {
+		return "", err
+	}
 
 	for k, v := range bytes {
 		bytes[k] = alphabet[v%byte(len(alphabet))]
 	}
 
-	return string(bytes)
+	return string(bytes), nil
 }
 
 // clearCookie invalidates and removes the cookie from the client.
@@ -343,33 +537,42 @@ func clearCookie(w http.ResponseWriter, c *http.Cookie) {
 }
 
 // setSession stores the session id to ticket mapping in the Client.
-func (c *Client) setSession(id string, ticket string) {
+func (c *Client) setSession(ctx context.Context, id string, ticket string) error {
 	if glog.V(2) {
 		glog.Infof("Recording session, %v -> %v", id, ticket)
 	}
 
-	c.sessions.Set(id, ticket)
+	return c.sessions.SetContext(ctx, id, ticket)
 }
 
 // clearSession removes the session from the client and clears the cookie.
 func (c *Client) clearSession(w http.ResponseWriter, r *http.Request) {
-	cookie := c.getCookie(w, r)
+	ctx := r.Context()
+	cookie, err := c.getCookie(w, r)
+	if err != nil {
+		return
+	}
 
-	if serviceTicket, ok := c.sessions.Get(cookie.Value); ok {
-		if err := c.tickets.Delete(serviceTicket); err != nil {
+	if serviceTicket, err := c.sessions.GetContext(ctx, cookie.Value); err == nil {
+		if err := c.tickets.DeleteContext(ctx, serviceTicket); err != nil {
 			fmt.Printf("Failed to remove %v from %T: %v\n", cookie.Value, c.tickets, err)
 			if glog.V(2) {
 				glog.Errorf("Failed to remove %v from %T: %v", cookie.Value, c.tickets, err)
 			}
 		}
 
-		c.deleteSession(cookie.Value)
+		c.deleteSession(ctx, cookie.Value)
+		c.invalidatePrincipal(serviceTicket)
 	}
 
 	clearCookie(w, cookie)
 }
 
 // deleteSession removes the session from the client
-func (c *Client) deleteSession(id string) {
-	c.sessions.Delete(id)
+func (c *Client) deleteSession(ctx context.Context, id string) {
+	if err := c.sessions.DeleteContext(ctx, id); err != nil {
+		if glog.V(2) {
+			glog.Errorf("Failed to remove session %v from %T: %v", id, c.sessions, err)
+		}
+	}
 }
@@ -1,12 +1,16 @@
 package cas
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestUnauthenticatedRequestShouldRedirectToCasURL(t *testing.T) {
@@ -487,7 +491,7 @@ func TestSingleLogOut(t *testing.T) {
 	}
 
 	// Single Logout Request
-	logoutRequest, err := xmlLogoutRequest(ticket.Name)
+	logoutRequest, err := xmlLogoutRequest(ticket.Name, client.clock, client.rand)
 	if err != nil {
 		t.Errorf("xmlLogoutRequest returned an error: %v", err)
 	}
@@ -513,3 +517,172 @@ func TestSingleLogOut(t *testing.T) {
 		t.Errorf("Expected tickets.Read error to be ErrInvalidTicket, got %v", err)
 	}
 }
+
+func TestConcurrentValidationsAreCoalesced(t *testing.T) {
+	server, ticket := newTestServerWithTicket()
+	defer server.Close()
+
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		server.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	client := NewClient(&Options{
+		URL: u,
+	})
+
+	handler := client.HandleFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAuthenticated(r) {
+			RedirectToLogin(w, r)
+			return
+		}
+
+		fmt.Fprintln(w, Username(r))
+	})
+
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			req, _ := http.NewRequest("GET", "http://example.com/?ticket="+ticket.Name, nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			codes[i] = w.Code
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected HTTP response code of request %d to be <%v>, got <%v>", i, http.StatusOK, code)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected a single validation request, got %v", calls)
+	}
+
+	// a repeated request within the reuse window is answered from the store
+	req, _ := http.NewRequest("GET", "http://example.com/?ticket="+ticket.Name, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected HTTP response code to be <%v>, got <%v>", http.StatusOK, w.Code)
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected a single validation request, got %v", calls)
+	}
+
+	// the reuse window is bound to the service
+	req, _ = http.NewRequest("GET", "http://example.com/other?ticket="+ticket.Name, nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if calls != 2 {
+		t.Errorf("Expected a second validation request, got %v", calls)
+	}
+}
+
+func TestTicketReuseWindowDisabled(t *testing.T) {
+	server, ticket := newTestServerWithTicket()
+	defer server.Close()
+
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		server.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	client := NewClient(&Options{
+		URL:               u,
+		TicketReuseWindow: -1,
+	})
+
+	handler := client.HandleFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "http://example.com/?ticket="+ticket.Name, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected 2 validation requests, got %v", calls)
+	}
+}
+
+func TestCoalescedValidationSurvivesCancelledCaller(t *testing.T) {
+	server, ticket := newTestServerWithTicket()
+	defer server.Close()
+
+	var calls int32
+	received := make(chan struct{})
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(received)
+		}
+
+		<-release
+		server.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	client := NewClient(&Options{
+		URL: u,
+	})
+
+	handler := client.HandleFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAuthenticated(r) {
+			RedirectToLogin(w, r)
+			return
+		}
+
+		fmt.Fprintln(w, Username(r))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan int)
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/?ticket="+ticket.Name, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		first <- w.Code
+	}()
+
+	<-received
+
+	second := make(chan int)
+	go func() {
+		req, _ := http.NewRequest("GET", "http://example.com/?ticket="+ticket.Name, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		second <- w.Code
+	}()
+
+	cancel()
+	if code := <-first; code != http.StatusFound {
+		t.Errorf("Expected cancelled request to be redirected with <%v>, got <%v>", http.StatusFound, code)
+	}
+
+	close(release)
+	if code := <-second; code != http.StatusOK {
+		t.Errorf("Expected HTTP response code of second request to be <%v>, got <%v>", http.StatusOK, code)
+	}
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected a single validation request, got %v", n)
+	}
+}
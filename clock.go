@@ -0,0 +1,131 @@
+package cas
+
+import (
+	"crypto/rand"
+	"io"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time to the time based behaviour of the package,
+// and controls how long it waits, e.g. between retries.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// After returns a channel receiving the current time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is a Clock reading the system time.
+type systemClock struct{}
+
+// Now returns the current system time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for d to elapse on the system clock
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// clockOrDefault returns c, or the system clock if c is nil.
+func clockOrDefault(c Clock) Clock {
+	if c == nil {
+		return systemClock{}
+	}
+
+	return c
+}
+
+// randOrDefault returns r, or crypto/rand if r is nil.
+func randOrDefault(r io.Reader) io.Reader {
+	if r == nil {
+		return rand.Reader
+	}
+
+	return r
+}
+
+// FakeClock is a Clock for tests which only moves when told to. Channels
+// returned by After receive once the clock is moved past their deadline.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+// fakeWaiter is a pending After call of a FakeClock
+type fakeWaiter struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+// NewFakeClock creates a FakeClock set to t.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+// Now returns the time the clock is set to
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel receiving the time once the clock has been moved
+// forward by d
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := fakeWaiter{until: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.ch <- c.now
+		return w.ch
+	}
+
+	c.waiters = append(c.waiters, w)
+	return w.ch
+}
+
+// Waiters returns the number of After calls waiting for the clock to move,
+// allowing tests to advance the clock once the code under test waits.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// Set moves the clock to t
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.wake()
+	c.mu.Unlock()
+}
+
+// Advance moves the clock forward by d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.wake()
+	c.mu.Unlock()
+}
+
+// wake releases the waiters whose deadline has passed. Must be called with
+// c.mu held.
+func (c *FakeClock) wake() {
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if c.now.Before(w.until) {
+			pending = append(pending, w)
+			continue
+		}
+
+		w.ch <- c.now
+	}
+
+	c.waiters = pending
+}
@@ -0,0 +1,124 @@
+package cas
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClock returns a FakeClock set to a fixed time
+func newTestClock() *FakeClock {
+	return NewFakeClock(time.Date(2015, 02, 27, 13, 31, 34, 0, time.UTC))
+}
+
+func TestFakeClock(t *testing.T) {
+	clock := newTestClock()
+	start := clock.Now()
+
+	clock.Advance(time.Minute)
+	if now := clock.Now(); !now.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected clock to advance by a minute, got %v", now)
+	}
+
+	clock.Set(start)
+	if now := clock.Now(); !now.Equal(start) {
+		t.Errorf("Expected clock to be set to %v, got %v", start, now)
+	}
+}
+
+func TestFakeClockAfter(t *testing.T) {
+	clock := newTestClock()
+	start := clock.Now()
+
+	after := clock.After(time.Hour)
+	if n := clock.Waiters(); n != 1 {
+		t.Errorf("Expected 1 waiter, got %d", n)
+	}
+
+	if now := clock.Now(); !now.Equal(start) {
+		t.Errorf("Expected After not to move the clock, got %v", now)
+	}
+
+	clock.Advance(30 * time.Minute)
+	select {
+	case <-after:
+		t.Fatalf("After fired before the clock reached its deadline")
+	default:
+	}
+
+	clock.Advance(30 * time.Minute)
+	if fired := <-after; !fired.Equal(start.Add(time.Hour)) {
+		t.Errorf("Expected After to receive %v, got %v", start.Add(time.Hour), fired)
+	}
+
+	if n := clock.Waiters(); n != 0 {
+		t.Errorf("Expected no waiters, got %d", n)
+	}
+
+	if fired := <-clock.After(0); !fired.Equal(clock.Now()) {
+		t.Errorf("Expected After(0) to fire immediately, got %v", fired)
+	}
+}
+
+func TestXmlLogoutRequestDeterministic(t *testing.T) {
+	clock := newTestClock()
+	entropy := bytes.NewReader(make([]byte, 64))
+
+	data, err := xmlLogoutRequest("ST-1", clock, entropy)
+	if err != nil {
+		t.Fatalf("xmlLogoutRequest failed: %v", err)
+	}
+
+	l, err := parseLogoutRequest(data)
+	if err != nil {
+		t.Fatalf("parseLogoutRequest failed: %v", err)
+	}
+
+	if l.ID != strings.Repeat("a", 64) {
+		t.Errorf("Expected ID derived from the entropy, got %q", l.ID)
+	}
+
+	if !clock.Now().Equal(l.IssueInstant) {
+		t.Errorf("Expected IssueInstant %v, got %v", clock.Now(), l.IssueInstant)
+	}
+
+	if l.SessionIndex != "ST-1" {
+		t.Errorf("Expected SessionIndex ST-1, got %q", l.SessionIndex)
+	}
+
+	if _, err := xmlLogoutRequest("ST-1", clock, entropy); err == nil {
+		t.Errorf("Expected exhausted entropy to fail")
+	}
+}
+
+func TestClientSessionIDFromRand(t *testing.T) {
+	u, _ := url.Parse("https://cas.example.com/")
+	client := NewClient(&Options{
+		URL:  u,
+		Rand: bytes.NewReader(make([]byte, 64)),
+	})
+
+	handler := client.HandleFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	cookies := (&http.Response{Header: w.Header()}).Cookies()
+	if len(cookies) != 1 || cookies[0].Value != strings.Repeat("a", 64) {
+		t.Errorf("Expected session cookie derived from the entropy, got %v", cookies)
+	}
+
+	// entropy source is exhausted, no session can be created
+	req, _ = http.NewRequest("GET", "http://example.com/", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if cookie := w.Header().Get("Set-Cookie"); cookie != "" {
+		t.Errorf("Expected no session cookie, got %q", cookie)
+	}
+}
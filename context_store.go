@@ -0,0 +1,109 @@
+package cas
+
+import (
+	"context"
+)
+
+// NewContextTicketStore returns s as a ContextTicketStore.
+//
+// If s already implements ContextTicketStore it is returned unchanged, otherwise
+// it is wrapped so the context methods check for cancellation before
+// delegating to the plain TicketStore methods.
+func NewContextTicketStore(s TicketStore) ContextTicketStore {
+	if cs, ok := s.(ContextTicketStore); ok {
+		return cs
+	}
+
+	return &contextTicketStore{s}
+}
+
+// contextTicketStore adapts a TicketStore to the ContextTicketStore interface.
+type contextTicketStore struct {
+	TicketStore
+}
+
+// ReadContext returns the AuthenticationResponse for a ticket
+func (s *contextTicketStore) ReadContext(ctx context.Context, id string) (*AuthenticationResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.Read(id)
+}
+
+// WriteContext stores the AuthenticationResponse for a ticket
+func (s *contextTicketStore) WriteContext(ctx context.Context, id string, ticket *AuthenticationResponse) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Write(id, ticket)
+}
+
+// DeleteContext removes the AuthenticationResponse for a ticket
+func (s *contextTicketStore) DeleteContext(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Delete(id)
+}
+
+// ClearContext removes all ticket data
+func (s *contextTicketStore) ClearContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Clear()
+}
+
+// NewContextSessionStore returns s as a ContextSessionStore.
+//
+// If s already implements ContextSessionStore it is returned unchanged, otherwise
+// it is wrapped so the context methods check for cancellation before
+// delegating to the plain SessionStore methods.
+func NewContextSessionStore(s SessionStore) ContextSessionStore {
+	if cs, ok := s.(ContextSessionStore); ok {
+		return cs
+	}
+
+	return &contextSessionStore{s}
+}
+
+// contextSessionStore adapts a SessionStore to the ContextSessionStore interface.
+type contextSessionStore struct {
+	SessionStore
+}
+
+// GetContext returns the ticket for the session id
+func (s *contextSessionStore) GetContext(ctx context.Context, sessionID string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	ticket, ok := s.Get(sessionID)
+	if !ok {
+		return "", ErrInvalidSession
+	}
+
+	return ticket, nil
+}
+
+// SetContext associates the session with a ticket
+func (s *contextSessionStore) SetContext(ctx context.Context, sessionID, ticket string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Set(sessionID, ticket)
+}
+
+// DeleteContext removes the session
+func (s *contextSessionStore) DeleteContext(ctx context.Context, sessionID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Delete(sessionID)
+}
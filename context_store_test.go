@@ -0,0 +1,94 @@
+package cas
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextTicketStore(t *testing.T) {
+	store := NewContextTicketStore(&MemoryStore{})
+	if store == nil {
+		t.Fatalf("Expected NewContextTicketStore to return a store")
+	}
+
+	ctx := context.Background()
+	user := &AuthenticationResponse{User: "user"}
+
+	if err := store.WriteContext(ctx, "ST-1", user); err != nil {
+		t.Fatalf("WriteContext failed: %v", err)
+	}
+
+	if ar, err := store.ReadContext(ctx, "ST-1"); err != nil || ar != user {
+		t.Errorf("Expected written response, got %v, %v", ar, err)
+	}
+
+	if err := store.DeleteContext(ctx, "ST-1"); err != nil {
+		t.Fatalf("DeleteContext failed: %v", err)
+	}
+
+	if _, err := store.ReadContext(ctx, "ST-1"); err != ErrInvalidTicket {
+		t.Errorf("Expected ErrInvalidTicket, got %v", err)
+	}
+}
+
+func TestContextTicketStore_Cancelled(t *testing.T) {
+	store := NewContextTicketStore(&MemoryStore{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := store.WriteContext(ctx, "ST-1", &AuthenticationResponse{User: "user"}); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if _, err := store.Read("ST-1"); err != ErrInvalidTicket {
+		t.Errorf("Expected cancelled write to be skipped, got %v", err)
+	}
+}
+
+func TestContextTicketStore_Passthrough(t *testing.T) {
+	store := NewContextTicketStore(&MemoryStore{})
+	if store != NewContextTicketStore(store) {
+		t.Errorf("Expected a ContextTicketStore to be returned unchanged")
+	}
+}
+
+func TestContextSessionStore(t *testing.T) {
+	ss := NewContextSessionStore(NewMemorySessionStore())
+	if ss == nil {
+		t.Fatalf("Expected NewContextSessionStore to return a store")
+	}
+
+	ctx := context.Background()
+
+	if _, err := ss.GetContext(ctx, "key1"); err != ErrInvalidSession {
+		t.Errorf("Expected ErrInvalidSession, got %v", err)
+	}
+
+	if err := ss.SetContext(ctx, "key1", "value1"); err != nil {
+		t.Fatalf("SetContext failed: %v", err)
+	}
+
+	if v, err := ss.GetContext(ctx, "key1"); err != nil || v != "value1" {
+		t.Errorf("Expected value1, got %q, %v", v, err)
+	}
+
+	if err := ss.DeleteContext(ctx, "key1"); err != nil {
+		t.Fatalf("DeleteContext failed: %v", err)
+	}
+
+	if _, err := ss.GetContext(ctx, "key1"); err != ErrInvalidSession {
+		t.Errorf("Expected ErrInvalidSession, got %v", err)
+	}
+}
+
+func TestContextSessionStore_Cancelled(t *testing.T) {
+	ss := NewContextSessionStore(NewMemorySessionStore())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ss.GetContext(ctx, "key1"); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package cas
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// Names of the attributes Apereo CAS encrypts with the public key of the service
+const (
+	CredentialAttribute          = "credential"
+	ProxyGrantingTicketAttribute = "proxyGrantingTicket"
+)
+
+// ErrAttributeDecryption is matched with errors.Is by errors decrypting an
+// encrypted attribute. Unrelated to ErrDecrypt of the EncryptedTicketStore.
+var ErrAttributeDecryption = errors.New("cas: attribute decryption failed")
+
+// errNoPrivateKey is the reason an attribute can not be decrypted without a private key
+var errNoPrivateKey = errors.New("no private key configured")
+
+// AttributeDecryptionError reports an encrypted attribute which could not be decrypted,
+// e.g. because it was encrypted for a different key.
+type AttributeDecryptionError struct {
+	Attribute string // Name of the encrypted attribute
+	Err       error  // Reason the attribute could not be decrypted
+}
+
+// Error returns the AttributeDecryptionError as a string
+func (e *AttributeDecryptionError) Error() string {
+	return fmt.Sprintf("cas: decrypt attribute %s: %v", e.Attribute, e.Err)
+}
+
+// Unwrap returns the reason the attribute could not be decrypted
+func (e *AttributeDecryptionError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrAttributeDecryption
+func (e *AttributeDecryptionError) Is(target error) bool {
+	return target == ErrAttributeDecryption
+}
+
+// Secret holds sensitive data, such as a decrypted credential. It is redacted
+// when formatted, use Reveal to obtain the value.
+type Secret string
+
+// String returns a redacted placeholder
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return "[redacted]"
+}
+
+// GoString returns a redacted placeholder
+func (s Secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+// MarshalText returns a redacted placeholder, keeping the secret out of
+// encoded output such as structured logs
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// MarshalJSON returns a redacted placeholder
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(s.String())), nil
+}
+
+// Reveal returns the secret value
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// DecryptCredential decrypts the credential attribute released by Apereo CAS,
+// encrypted with the public key of the service using RSA PKCS #1 v1.5 and
+// base64 encoded. An empty Secret is returned if the attribute was not released.
+//
+// The attribute stays encrypted in Attributes, so responses kept by a
+// TicketStore can be decrypted again.
+func (a *AuthenticationResponse) DecryptCredential(key crypto.Decrypter) (Secret, error) {
+	return a.decryptAttribute(CredentialAttribute, key)
+}
+
+// DecryptProxyGrantingTicket decrypts the proxyGrantingTicket attribute
+// released by Apereo CAS, see DecryptCredential.
+func (a *AuthenticationResponse) DecryptProxyGrantingTicket(key crypto.Decrypter) (Secret, error) {
+	return a.decryptAttribute(ProxyGrantingTicketAttribute, key)
+}
+
+// decryptAttribute decodes and decrypts the first value of the attribute
+func (a *AuthenticationResponse) decryptAttribute(name string, key crypto.Decrypter) (Secret, error) {
+	if a == nil {
+		return "", nil
+	}
+
+	encrypted := a.Attributes.getFold(name)
+	if encrypted == "" {
+		return "", nil
+	}
+
+	if key == nil {
+		return "", &AttributeDecryptionError{Attribute: name, Err: errNoPrivateKey}
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encrypted), ""))
+	if err != nil {
+		return "", &AttributeDecryptionError{Attribute: name, Err: err}
+	}
+
+	plaintext, err := key.Decrypt(rand.Reader, ciphertext, nil)
+	if err != nil {
+		return "", &AttributeDecryptionError{Attribute: name, Err: err}
+	}
+
+	return Secret(plaintext), nil
+}
+
+// Credential returns the decrypted password of the authenticated user, if
+// released by the CAS server. It is decrypted with the PrivateKey of the Client
+// or RestClient handling the request, use DecryptCredential to learn why
+// decryption failed.
+func Credential(r *http.Request) Secret {
+	return decryptRequestAttribute(r, CredentialAttribute)
+}
+
+// ProxyGrantingTicket returns the decrypted proxy granting ticket of the
+// authenticated user, if released by the CAS server, see Credential.
+func ProxyGrantingTicket(r *http.Request) Secret {
+	return decryptRequestAttribute(r, ProxyGrantingTicketAttribute)
+}
+
+// decryptRequestAttribute decrypts the attribute of the authenticated user
+func decryptRequestAttribute(r *http.Request, name string) Secret {
+	s, err := getAuthenticationResponse(r).decryptAttribute(name, getPrivateKey(r))
+	if err != nil {
+		if glog.V(1) {
+			glog.Infof("%v", err)
+		}
+	}
+
+	return s
+}
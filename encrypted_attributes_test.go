@@ -0,0 +1,178 @@
+package cas
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func encryptTestAttribute(t *testing.T, key *rsa.PrivateKey, value string) string {
+	ciphertext, err := rsa.EncryptPKCS1v15(rand.Reader, &key.PublicKey, []byte(value))
+	if err != nil {
+		t.Fatalf("EncryptPKCS1v15 failed: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(ciphertext)
+}
+
+func TestClientDecryptsAttributes(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	credential := encryptTestAttribute(t, key, "hunter2")
+	pgt := encryptTestAttribute(t, key, "PGT-1-abc")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+    <cas:user>alice</cas:user>
+    <cas:attributes>
+      <cas:credential>%s</cas:credential>
+      <cas:proxyGrantingTicket>%s</cas:proxyGrantingTicket>
+      <cas:mail>alice@example.com</cas:mail>
+    </cas:attributes>
+  </cas:authenticationSuccess>
+</cas:serviceResponse>`, credential, pgt)
+	}))
+	defer server.Close()
+
+	// the encrypted store keeps responses as JSON, decryption must survive it
+	store, err := NewEncryptedTicketStore(&MemoryStore{}, testHMACKey, testKeyA)
+	if err != nil {
+		t.Fatalf("NewEncryptedTicketStore failed: %v", err)
+	}
+
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	client := NewClient(&Options{
+		URL:        casURL,
+		Client:     server.Client(),
+		Store:      store,
+		PrivateKey: key,
+	})
+
+	var secrets []Secret
+	var formatted string
+	handler := client.HandleFunc(func(w http.ResponseWriter, r *http.Request) {
+		secrets = []Secret{Credential(r), ProxyGrantingTicket(r)}
+		formatted = fmt.Sprintf("%v %+v %#v", Authentication(r), secrets, secrets)
+	})
+
+	r := httptest.NewRequest("GET", "https://service.example.com/?ticket=ST-1", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if len(secrets) != 2 || secrets[0].Reveal() != "hunter2" || secrets[1].Reveal() != "PGT-1-abc" {
+		t.Fatalf("Expected decrypted credential and proxy granting ticket, got %q", secrets)
+	}
+
+	if strings.Contains(formatted, "hunter2") || strings.Contains(formatted, "PGT-1-abc") {
+		t.Errorf("Expected secrets to be redacted, got %s", formatted)
+	}
+
+	// the second request reads the response back from the store
+	secrets = nil
+	r = httptest.NewRequest("GET", "https://service.example.com/", nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(secrets) != 2 || secrets[0].Reveal() != "hunter2" || secrets[1].Reveal() != "PGT-1-abc" {
+		t.Errorf("Expected decrypted values after reading from the store, got %q", secrets)
+	}
+}
+
+func TestParseServiceResponseDecryptAttributes(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	data := fmt.Sprintf(`<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+    <cas:user>alice</cas:user>
+    <cas:attributes>
+      <cas:credential>%s</cas:credential>
+    </cas:attributes>
+  </cas:authenticationSuccess>
+</cas:serviceResponse>`, encryptTestAttribute(t, key, "hunter2"))
+
+	a, err := ParseServiceResponse([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseServiceResponse failed: %v", err)
+	}
+
+	credential, err := a.DecryptCredential(key)
+	if err != nil || credential.Reveal() != "hunter2" {
+		t.Errorf("Expected decrypted credential, got %q, %v", credential.Reveal(), err)
+	}
+
+	pgt, err := a.DecryptProxyGrantingTicket(key)
+	if err != nil || pgt != "" {
+		t.Errorf("Expected no proxy granting ticket, got %q, %v", pgt.Reveal(), err)
+	}
+}
+
+func TestDecryptAttributeErrors(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	a := &AuthenticationResponse{
+		Attributes: UserAttributes{CredentialAttribute: {encryptTestAttribute(t, key, "hunter2")}},
+	}
+
+	_, err := a.DecryptCredential(other)
+	var de *AttributeDecryptionError
+	if !errors.Is(err, ErrAttributeDecryption) || !errors.As(err, &de) || de.Attribute != CredentialAttribute {
+		t.Errorf("Expected AttributeDecryptionError for the wrong key, got <%v>", err)
+	}
+
+	if _, err := a.DecryptCredential(nil); !errors.Is(err, ErrAttributeDecryption) {
+		t.Errorf("Expected AttributeDecryptionError without a key, got <%v>", err)
+	}
+
+	r := httptest.NewRequest("GET", "https://service.example.com/", nil)
+	setAuthenticationResponse(r, a)
+	setPrivateKey(r, other)
+
+	if s := Credential(r); s != "" {
+		t.Errorf("Expected empty credential for the wrong key, got %q", s.Reveal())
+	}
+
+	a.Attributes = UserAttributes{CredentialAttribute: {"not base64!"}}
+	if _, err := a.DecryptCredential(key); !errors.Is(err, ErrAttributeDecryption) {
+		t.Errorf("Expected AttributeDecryptionError for invalid base64, got <%v>", err)
+	}
+}
+
+func TestSecretIsRedactedWhenEncoded(t *testing.T) {
+	secret := Secret("hunter2")
+
+	data, err := json.Marshal(struct {
+		Credential Secret
+		Secrets    map[Secret]Secret
+	}{secret, map[Secret]Secret{secret: secret}})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	if strings.Contains(string(data), "hunter2") {
+		t.Errorf("Expected secret to be redacted, got %s", data)
+	}
+
+	if text, _ := secret.MarshalText(); string(text) != "[redacted]" {
+		t.Errorf("Expected redacted text, got %s", text)
+	}
+
+	if secret.Reveal() != "hunter2" {
+		t.Errorf("Expected Reveal to return the secret")
+	}
+}
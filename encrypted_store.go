@@ -0,0 +1,280 @@
+package cas
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Attribute names of the envelope handed to the backing store of an EncryptedTicketStore
+const (
+	encryptedKeyAttribute  = "cas:encrypted:key"
+	encryptedDataAttribute = "cas:encrypted:data"
+)
+
+// EncryptedTicketStore errors
+var (
+	// The stored ticket data was not written by an EncryptedTicketStore
+	ErrNotEncrypted = errors.New("cas: encrypted store: ticket data is not encrypted")
+
+	// The stored ticket data could not be authenticated and decrypted
+	ErrDecrypt = errors.New("cas: encrypted store: unable to decrypt ticket data")
+)
+
+// EncryptionKey is an AES key used by an EncryptedTicketStore.
+type EncryptionKey struct {
+	ID  string // Identifies the key within stored ticket data
+	Key []byte // 16, 24 or 32 bytes selecting AES-128, AES-192 or AES-256
+}
+
+// EncryptedTicketStore is a TicketStore which encrypts AuthenticationResponse
+// data with AES-GCM before handing it to a backing TicketStore.
+//
+// The backing store receives an AuthenticationResponse envelope whose only
+// content is the key id and ciphertext held in Attributes. Ticket ids are
+// replaced with their HMAC-SHA256 so raw tickets never reach the backing store.
+type EncryptedTicketStore struct {
+	backend ContextTicketStore
+	hmacKey []byte
+
+	mu      sync.RWMutex
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewEncryptedTicketStore creates an EncryptedTicketStore in front of backend.
+//
+// hmacKey is used to hash ticket ids and must remain the same for the lifetime
+// of the stored data. The first of keys encrypts new data, the remainder are
+// only used to decrypt data written before a key rotation.
+func NewEncryptedTicketStore(backend TicketStore, hmacKey []byte, keys ...EncryptionKey) (*EncryptedTicketStore, error) {
+	if len(hmacKey) == 0 {
+		return nil, errors.New("cas: encrypted store: hmac key is required")
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("cas: encrypted store: at least one encryption key is required")
+	}
+
+	s := &EncryptedTicketStore{
+		backend: NewContextTicketStore(backend),
+		hmacKey: hmacKey,
+		keys:    make(map[string]cipher.AEAD),
+	}
+
+	for i := len(keys) - 1; i >= 0; i-- {
+		if err := s.Rotate(keys[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Rotate adds key to the store and makes it the key used to encrypt new data.
+// Previously added keys remain available for decryption.
+func (s *EncryptedTicketStore) Rotate(key EncryptionKey) error {
+	if key.ID == "" {
+		return errors.New("cas: encrypted store: key id is required")
+	}
+
+	block, err := aes.NewCipher(key.Key)
+	if err != nil {
+		return fmt.Errorf("cas: encrypted store: key %q: %v", key.ID, err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("cas: encrypted store: key %q: %v", key.ID, err)
+	}
+
+	s.mu.Lock()
+	s.keys[key.ID] = aead
+	s.primary = key.ID
+	s.mu.Unlock()
+
+	return nil
+}
+
+// RemoveKey retires a key which is no longer used to encrypt data. Data
+// encrypted with the key can no longer be read. The current encryption key
+// can not be removed.
+func (s *EncryptedTicketStore) RemoveKey(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id == s.primary {
+		return fmt.Errorf("cas: encrypted store: key %q is the current encryption key", id)
+	}
+
+	delete(s.keys, id)
+	return nil
+}
+
+// Read returns the AuthenticationResponse for a ticket
+func (s *EncryptedTicketStore) Read(id string) (*AuthenticationResponse, error) {
+	return s.ReadContext(context.Background(), id)
+}
+
+// ReadContext returns the decrypted AuthenticationResponse for a ticket
+func (s *EncryptedTicketStore) ReadContext(ctx context.Context, id string) (*AuthenticationResponse, error) {
+	t, _, err := s.read(ctx, id)
+	return t, err
+}
+
+// Write stores the AuthenticationResponse for a ticket
+func (s *EncryptedTicketStore) Write(id string, ticket *AuthenticationResponse) error {
+	return s.WriteContext(context.Background(), id, ticket)
+}
+
+// WriteContext encrypts the AuthenticationResponse with the current key and
+// stores it in the backing store.
+func (s *EncryptedTicketStore) WriteContext(ctx context.Context, id string, ticket *AuthenticationResponse) error {
+	key := s.key(id)
+
+	envelope, err := s.seal(key, ticket)
+	if err != nil {
+		return err
+	}
+
+	return s.backend.WriteContext(ctx, key, envelope)
+}
+
+// Delete removes the AuthenticationResponse for a ticket
+func (s *EncryptedTicketStore) Delete(id string) error {
+	return s.DeleteContext(context.Background(), id)
+}
+
+// DeleteContext removes the AuthenticationResponse for a ticket from the backing store.
+func (s *EncryptedTicketStore) DeleteContext(ctx context.Context, id string) error {
+	return s.backend.DeleteContext(ctx, s.key(id))
+}
+
+// Clear removes all ticket data
+func (s *EncryptedTicketStore) Clear() error {
+	return s.ClearContext(context.Background())
+}
+
+// ClearContext removes all ticket data from the backing store.
+func (s *EncryptedTicketStore) ClearContext(ctx context.Context) error {
+	return s.backend.ClearContext(ctx)
+}
+
+// Reencrypt rewrites the data for a ticket with the current encryption key.
+func (s *EncryptedTicketStore) Reencrypt(id string) error {
+	return s.ReencryptContext(context.Background(), id)
+}
+
+// ReencryptContext rewrites the data for a ticket with the current encryption
+// key. Data already encrypted with the current key is left untouched.
+func (s *EncryptedTicketStore) ReencryptContext(ctx context.Context, id string) error {
+	t, keyID, err := s.read(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	current := keyID == s.primary
+	s.mu.RUnlock()
+
+	if current {
+		return nil
+	}
+
+	return s.WriteContext(ctx, id, t)
+}
+
+// read returns the decrypted AuthenticationResponse for a ticket and the id of
+// the key it was encrypted with.
+func (s *EncryptedTicketStore) read(ctx context.Context, id string) (*AuthenticationResponse, string, error) {
+	key := s.key(id)
+
+	envelope, err := s.backend.ReadContext(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return s.open(key, envelope)
+}
+
+// key returns the backing store key for a ticket id.
+func (s *EncryptedTicketStore) key(id string) string {
+	mac := hmac.New(sha256.New, s.hmacKey)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// seal encrypts ticket into an envelope bound to the backing store key.
+func (s *EncryptedTicketStore) seal(key string, ticket *AuthenticationResponse) (*AuthenticationResponse, error) {
+	plaintext, err := json.Marshal(ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	keyID := s.primary
+	aead := s.keys[keyID]
+	s.mu.RUnlock()
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	data := aead.Seal(nonce, nonce, plaintext, []byte(key))
+
+	envelope := &AuthenticationResponse{Attributes: make(UserAttributes)}
+	envelope.Attributes.Add(encryptedKeyAttribute, keyID)
+	envelope.Attributes.Add(encryptedDataAttribute, base64.StdEncoding.EncodeToString(data))
+
+	return envelope, nil
+}
+
+// open decrypts an envelope written by seal.
+func (s *EncryptedTicketStore) open(key string, envelope *AuthenticationResponse) (*AuthenticationResponse, string, error) {
+	if envelope == nil || envelope.Attributes == nil {
+		return nil, "", ErrNotEncrypted
+	}
+
+	keyID := envelope.Attributes.Get(encryptedKeyAttribute)
+	encoded := envelope.Attributes.Get(encryptedDataAttribute)
+	if keyID == "" || encoded == "" {
+		return nil, "", ErrNotEncrypted
+	}
+
+	s.mu.RLock()
+	aead, ok := s.keys[keyID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, "", fmt.Errorf("cas: encrypted store: unknown key %q", keyID)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < aead.NonceSize() {
+		return nil, "", ErrDecrypt
+	}
+
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return nil, "", ErrDecrypt
+	}
+
+	t := &AuthenticationResponse{}
+	if err := json.Unmarshal(plaintext, t); err != nil {
+		return nil, "", ErrDecrypt
+	}
+
+	return t, keyID, nil
+}
@@ -0,0 +1,150 @@
+package cas
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	testHMACKey = []byte("0123456789abcdef0123456789abcdef")
+	testKeyA    = EncryptionKey{ID: "a", Key: bytes.Repeat([]byte{'a'}, 32)}
+	testKeyB    = EncryptionKey{ID: "b", Key: bytes.Repeat([]byte{'b'}, 32)}
+)
+
+func TestEncryptedTicketStore(t *testing.T) {
+	backend := &MemoryStore{}
+	store, err := NewEncryptedTicketStore(backend, testHMACKey, testKeyA)
+	if err != nil {
+		t.Fatalf("NewEncryptedTicketStore failed: %v", err)
+	}
+
+	user := &AuthenticationResponse{
+		User:       "user1",
+		Attributes: UserAttributes{"email": []string{"user1@example.org"}},
+	}
+
+	if err := store.Write("ST-1", user); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if ar, err := store.Read("ST-1"); err != nil || !reflect.DeepEqual(user, ar) {
+		t.Errorf("Expected %v, got %v, %v", user, ar, err)
+	}
+
+	if len(backend.store) != 1 {
+		t.Errorf("Expected 1 entry in the backing store, got %d", len(backend.store))
+	}
+
+	for id, e := range backend.store {
+		if strings.Contains(id, "ST-1") {
+			t.Errorf("Expected ticket to be hashed, got %q", id)
+		}
+
+		if e.response.User != "" || strings.Contains(e.response.Attributes.Get(encryptedDataAttribute), "user1") {
+			t.Errorf("Expected response to be encrypted, got %v", e.response)
+		}
+	}
+
+	if err := store.Delete("ST-1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if _, err := store.Read("ST-1"); err != ErrInvalidTicket {
+		t.Errorf("Expected ErrInvalidTicket, got %v", err)
+	}
+}
+
+func TestEncryptedTicketStore_Rotate(t *testing.T) {
+	backend := &MemoryStore{}
+	store, err := NewEncryptedTicketStore(backend, testHMACKey, testKeyA)
+	if err != nil {
+		t.Fatalf("NewEncryptedTicketStore failed: %v", err)
+	}
+
+	user := &AuthenticationResponse{User: "user1"}
+	if err := store.Write("ST-1", user); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := store.Rotate(testKeyB); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+
+	if err := store.RemoveKey("b"); err == nil {
+		t.Errorf("Expected the active key not to be removable")
+	}
+
+	if ar, err := store.Read("ST-1"); err != nil || !reflect.DeepEqual(user, ar) {
+		t.Errorf("Expected data written with the previous key to be readable, got %v, %v", ar, err)
+	}
+
+	if err := store.Reencrypt("ST-1"); err != nil {
+		t.Fatalf("Reencrypt failed: %v", err)
+	}
+
+	if err := store.RemoveKey("a"); err != nil {
+		t.Fatalf("RemoveKey failed: %v", err)
+	}
+
+	if ar, err := store.Read("ST-1"); err != nil || !reflect.DeepEqual(user, ar) {
+		t.Errorf("Expected reencrypted data to be readable, got %v, %v", ar, err)
+	}
+
+	// Data written with a retired key can no longer be read
+	old, err := NewEncryptedTicketStore(backend, testHMACKey, testKeyA)
+	if err != nil {
+		t.Fatalf("NewEncryptedTicketStore failed: %v", err)
+	}
+
+	if _, err := old.Read("ST-1"); err == nil {
+		t.Errorf("Expected retired key to fail decryption")
+	}
+}
+
+func TestEncryptedTicketStore_Tampered(t *testing.T) {
+	backend := &MemoryStore{}
+	store, err := NewEncryptedTicketStore(backend, testHMACKey, testKeyA)
+	if err != nil {
+		t.Fatalf("NewEncryptedTicketStore failed: %v", err)
+	}
+
+	if err := store.Write("ST-1", &AuthenticationResponse{User: "user1"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := store.Write("ST-2", &AuthenticationResponse{User: "user2"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	// Swapping ciphertext between tickets must fail authentication
+	k1, k2 := store.key("ST-1"), store.key("ST-2")
+	backend.store[k1], backend.store[k2] = backend.store[k2], backend.store[k1]
+
+	if _, err := store.Read("ST-1"); err != ErrDecrypt {
+		t.Errorf("Expected ErrDecrypt, got %v", err)
+	}
+
+	if err := backend.Write(store.key("ST-3"), &AuthenticationResponse{User: "user3"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if _, err := store.Read("ST-3"); err != ErrNotEncrypted {
+		t.Errorf("Expected ErrNotEncrypted, got %v", err)
+	}
+}
+
+func TestNewEncryptedTicketStore_InvalidKeys(t *testing.T) {
+	if _, err := NewEncryptedTicketStore(&MemoryStore{}, nil, testKeyA); err == nil {
+		t.Errorf("Expected missing hmac key to be rejected")
+	}
+
+	if _, err := NewEncryptedTicketStore(&MemoryStore{}, testHMACKey); err == nil {
+		t.Errorf("Expected missing encryption key to be rejected")
+	}
+
+	if _, err := NewEncryptedTicketStore(&MemoryStore{}, testHMACKey, EncryptionKey{ID: "short", Key: []byte("short")}); err == nil {
+		t.Errorf("Expected short encryption key to be rejected")
+	}
+}
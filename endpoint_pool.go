@@ -0,0 +1,138 @@
+package cas
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultEndpointCooldown is the time an unavailable cas server is skipped
+// for before back-channel requests are sent to it again.
+const DefaultEndpointCooldown = 30 * time.Second
+
+// EndpointStrategy selects how back-channel requests are spread over multiple
+// cas servers.
+type EndpointStrategy int
+
+// EndpointStrategy values
+const (
+	// FailoverStrategy sends requests to the first available server in the
+	// configured order.
+	FailoverStrategy EndpointStrategy = iota
+
+	// RoundRobinStrategy spreads requests evenly over the available servers.
+	RoundRobinStrategy
+)
+
+// endpointPool tracks the availability of the cas servers used for
+// back-channel requests.
+type endpointPool struct {
+	strategy EndpointStrategy
+	cooldown time.Duration
+	clock    Clock
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+	next      int
+}
+
+// endpoint is a cas server in an endpointPool
+type endpoint struct {
+	scheme         URLScheme
+	unhealthyUntil time.Time
+	lastError      error
+}
+
+// newEndpointPool creates an endpointPool over the given URLSchemes.
+func newEndpointPool(strategy EndpointStrategy, clock Clock, schemes ...URLScheme) *endpointPool {
+	p := &endpointPool{
+		strategy: strategy,
+		cooldown: DefaultEndpointCooldown,
+		clock:    clockOrDefault(clock),
+	}
+
+	for _, scheme := range schemes {
+		p.endpoints = append(p.endpoints, &endpoint{scheme: scheme})
+	}
+
+	return p
+}
+
+// newURLEndpointPool creates an endpointPool using the default cas urls of
+// each base url.
+func newURLEndpointPool(strategy EndpointStrategy, clock Clock, urls ...*url.URL) *endpointPool {
+	var schemes []URLScheme
+	for _, u := range urls {
+		schemes = append(schemes, NewDefaultURLScheme(u))
+	}
+
+	return newEndpointPool(strategy, clock, schemes...)
+}
+
+// primary returns the endpoint the next request would be sent to first.
+func (p *endpointPool) primary() *endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.order(false)[0]
+}
+
+// candidates returns the endpoints to try for a request, in order. Available
+// endpoints come first, followed by those still cooling down.
+func (p *endpointPool) candidates() []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.order(true)
+}
+
+// order returns the endpoints in the order they should be tried, advancing the
+// round robin position if requested. Must be called with p.mu held.
+func (p *endpointPool) order(advance bool) []*endpoint {
+	start := 0
+	if p.strategy == RoundRobinStrategy && len(p.endpoints) > 0 {
+		start = p.next % len(p.endpoints)
+		if advance {
+			p.next = start + 1
+		}
+	}
+
+	now := p.clock.Now()
+	healthy := make([]*endpoint, 0, len(p.endpoints))
+	var unhealthy []*endpoint
+
+	for i := range p.endpoints {
+		e := p.endpoints[(start+i)%len(p.endpoints)]
+		if now.Before(e.unhealthyUntil) {
+			unhealthy = append(unhealthy, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+// markFailure records the cas server as unavailable for the cooldown period.
+func (p *endpointPool) markFailure(e *endpoint, err error) {
+	p.mu.Lock()
+	e.unhealthyUntil = p.clock.Now().Add(p.cooldown)
+	e.lastError = err
+	p.mu.Unlock()
+}
+
+// markSuccess records the cas server as available.
+func (p *endpointPool) markSuccess(e *endpoint) {
+	p.mu.Lock()
+	e.unhealthyUntil = time.Time{}
+	e.lastError = nil
+	p.mu.Unlock()
+}
+
+// all returns every endpoint in the configured order.
+func (p *endpointPool) all() []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*endpoint(nil), p.endpoints...)
+}
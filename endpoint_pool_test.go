@@ -0,0 +1,126 @@
+package cas
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func poolURLs(t *testing.T, n int) []*url.URL {
+	var urls []*url.URL
+	for i := 0; i < n; i++ {
+		u, err := url.Parse(fmt.Sprintf("https://cas%d.example.com/cas", i))
+		if err != nil {
+			t.Fatalf("url.Parse failed: %v", err)
+		}
+		urls = append(urls, u)
+	}
+
+	return urls
+}
+
+func poolHosts(endpoints []*endpoint) []string {
+	var hosts []string
+	for _, e := range endpoints {
+		u, _ := e.scheme.Login()
+		hosts = append(hosts, u.Host)
+	}
+
+	return hosts
+}
+
+// expectCandidates checks the order in which the pool offers its endpoints
+func expectCandidates(t *testing.T, p *endpointPool, expected ...string) {
+	t.Helper()
+
+	if hosts := poolHosts(p.candidates()); !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("Expected candidates %v, got %v", expected, hosts)
+	}
+}
+
+func TestEndpointPoolFailover(t *testing.T) {
+	clock := newTestClock()
+	p := newURLEndpointPool(FailoverStrategy, clock, poolURLs(t, 3)...)
+
+	expectCandidates(t, p, "cas0.example.com", "cas1.example.com", "cas2.example.com")
+	expectCandidates(t, p, "cas0.example.com", "cas1.example.com", "cas2.example.com")
+
+	p.markFailure(p.endpoints[0], errors.New("down"))
+	expectCandidates(t, p, "cas1.example.com", "cas2.example.com", "cas0.example.com")
+
+	clock.Advance(DefaultEndpointCooldown)
+	expectCandidates(t, p, "cas0.example.com", "cas1.example.com", "cas2.example.com")
+}
+
+func TestEndpointPoolRoundRobin(t *testing.T) {
+	p := newURLEndpointPool(RoundRobinStrategy, nil, poolURLs(t, 3)...)
+
+	expectCandidates(t, p, "cas0.example.com", "cas1.example.com", "cas2.example.com")
+	expectCandidates(t, p, "cas1.example.com", "cas2.example.com", "cas0.example.com")
+
+	p.markFailure(p.endpoints[0], errors.New("down"))
+	expectCandidates(t, p, "cas2.example.com", "cas1.example.com", "cas0.example.com")
+
+	p.markSuccess(p.endpoints[0])
+	expectCandidates(t, p, "cas0.example.com", "cas1.example.com", "cas2.example.com")
+}
+
+func TestClientValidationFailover(t *testing.T) {
+	server, ticket := newTestServerWithTicket()
+	defer server.Close()
+
+	var calls int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		server.ServeHTTP(w, r)
+	}))
+	defer healthy.Close()
+
+	down := httptest.NewServer(server)
+	down.Close()
+
+	loginURL, _ := url.Parse("https://cas.example.com/")
+	downURL, _ := url.Parse(down.URL)
+	healthyURL, _ := url.Parse(healthy.URL)
+
+	client := NewClient(&Options{
+		URL:            loginURL,
+		ValidationURLs: []*url.URL{downURL, healthyURL},
+	})
+
+	handler := client.HandleFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAuthenticated(r) {
+			RedirectToLogin(w, r)
+			return
+		}
+
+		fmt.Fprintln(w, Username(r))
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com/?ticket="+ticket.Name, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != "enoch.root\n" {
+		t.Errorf("Expected enoch.root to be authenticated, got %d %q", w.Code, w.Body.String())
+	}
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected 1 validation by the healthy server, got %d", n)
+	}
+
+	// browser redirects keep going to the login url
+	req, _ = http.NewRequest("GET", "http://example.com/", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	expected := "https://cas.example.com/login?service=http%3A%2F%2Fexample.com%2F"
+	if loc := w.Header().Get("Location"); w.Code != http.StatusFound || loc != expected {
+		t.Errorf("Expected redirect to %v, got %d %q", expected, w.Code, loc)
+	}
+}
@@ -0,0 +1,145 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Errors matched with errors.Is against the errors returned by ticket
+// validation and the RestClient.
+var (
+	// The request could not be sent to, or the response not received from, the cas server
+	ErrTransport = errors.New("cas: transport failure")
+
+	// The cas server answered with an unexpected HTTP status code
+	ErrUnexpectedStatus = errors.New("cas: unexpected status")
+
+	// The cas server answered with a response which could not be understood
+	ErrMalformedResponse = errors.New("cas: malformed response")
+
+	// The cas server rejected the ticket, see AuthenticationError for the reason
+	ErrTicketRejected = errors.New("cas: ticket rejected")
+
+	// The cas server rejected the username and password of a REST request
+	ErrInvalidCredentials = errors.New("cas: invalid credentials")
+)
+
+// errMissingLocation is the reason a granting ticket response without Location header is malformed
+var errMissingLocation = errors.New("cas: missing location header")
+
+// TransportError reports a request which failed before a response was received
+type TransportError struct {
+	Op  string // Operation being performed, e.g. "validate ticket"
+	URL string // URL of the request
+	Err error  // Underlying error from the http.Client
+}
+
+// Error returns the TransportError as a string
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("cas: %s: %v", e.Op, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrTransport
+func (e *TransportError) Is(target error) bool {
+	return target == ErrTransport
+}
+
+// StatusError reports a response with an unexpected HTTP status code
+type StatusError struct {
+	Op         string // Operation being performed, e.g. "validate ticket"
+	URL        string // URL of the request
+	StatusCode int    // HTTP status code of the response
+	Body       string // Body of the response, if read
+}
+
+// maxErrorBody is the length of a response body included in error messages
+const maxErrorBody = 128
+
+// Error returns the StatusError as a string, with the body shortened
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("cas: %s: unexpected status %d %s", e.Op, e.StatusCode, http.StatusText(e.StatusCode))
+	if body := strings.TrimSpace(e.Body); body != "" {
+		msg += ": " + truncate(body, maxErrorBody)
+	}
+
+	return msg
+}
+
+// Is reports whether target is ErrUnexpectedStatus
+func (e *StatusError) Is(target error) bool {
+	return target == ErrUnexpectedStatus
+}
+
+// MalformedResponseError reports a response which could not be understood
+type MalformedResponseError struct {
+	Response string // Kind of response, e.g. "service response"
+	Err      error  // Reason the response was rejected
+}
+
+// Error returns the MalformedResponseError as a string
+func (e *MalformedResponseError) Error() string {
+	return fmt.Sprintf("cas: malformed %s: %s", e.Response, strings.TrimPrefix(e.Err.Error(), "cas: "))
+}
+
+// Unwrap returns the reason the response was rejected
+func (e *MalformedResponseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrMalformedResponse
+func (e *MalformedResponseError) Is(target error) bool {
+	return target == ErrMalformedResponse
+}
+
+// CredentialError reports a REST request for a granting ticket rejected by the cas server
+type CredentialError struct {
+	Username   string // Username the granting ticket was requested for
+	StatusCode int    // HTTP status code of the response
+}
+
+// Error returns the CredentialError as a string
+func (e *CredentialError) Error() string {
+	return fmt.Sprintf("cas: request granting ticket: credentials rejected for %q (status %d)", e.Username, e.StatusCode)
+}
+
+// Is reports whether target is ErrInvalidCredentials
+func (e *CredentialError) Is(target error) bool {
+	return target == ErrInvalidCredentials
+}
+
+// newTransportError wraps an error returned by the http.Client in a
+// TransportError, unless it was caused by ctx ending.
+func newTransportError(ctx context.Context, op string, url string, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+
+	return &TransportError{Op: op, URL: url, Err: err}
+}
+
+// isRetryable reports whether err was caused by the cas server being unavailable.
+func isRetryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode >= http.StatusInternalServerError
+	}
+
+	return errors.Is(err, ErrTransport)
+}
+
+// truncate shortens s to at most n bytes for inclusion in error messages.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	return s[:n] + "..."
+}
@@ -0,0 +1,155 @@
+package cas
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestValidateTicketErrors(t *testing.T) {
+	tests := map[string]struct {
+		handler func(w http.ResponseWriter, r *http.Request)
+		target  error
+	}{
+		"unexpected status": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+			target: ErrUnexpectedStatus,
+		},
+		"malformed response": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("<html>"))
+			},
+			target: ErrMalformedResponse,
+		},
+		"ticket rejected": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationFailure code="INVALID_TICKET">Ticket ST-1 not recognized</cas:authenticationFailure>
+</cas:serviceResponse>`))
+			},
+			target: ErrTicketRejected,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(tc.handler))
+			defer server.Close()
+
+			casURL, _ := url.Parse(server.URL + "/cas/")
+			serviceURL, _ := url.Parse("https://service.example.com/")
+			validator := NewServiceTicketValidator(server.Client(), casURL)
+
+			_, err := validator.ValidateTicket(serviceURL, "ST-1")
+			if !errors.Is(err, tc.target) {
+				t.Errorf("Expected error matching <%v>, got <%v>", tc.target, err)
+			}
+		})
+	}
+}
+
+func TestValidateTicketRejectedCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationFailure code="INVALID_SERVICE">Service mismatch</cas:authenticationFailure>
+</cas:serviceResponse>`))
+	}))
+	defer server.Close()
+
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	serviceURL, _ := url.Parse("https://service.example.com/")
+	validator := NewServiceTicketValidator(server.Client(), casURL)
+
+	_, err := validator.ValidateTicket(serviceURL, "ST-1")
+
+	var authErr *AuthenticationError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("Expected AuthenticationError, got <%v>", err)
+	}
+
+	if authErr.Code != INVALID_SERVICE {
+		t.Errorf("Expected code %v, got %v", INVALID_SERVICE, authErr.Code)
+	}
+}
+
+func TestValidateTicketTransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	server.Close()
+
+	serviceURL, _ := url.Parse("https://service.example.com/")
+	validator := NewServiceTicketValidator(http.DefaultClient, casURL)
+
+	_, err := validator.ValidateTicket(serviceURL, "ST-1")
+	if !errors.Is(err, ErrTransport) {
+		t.Fatalf("Expected error matching ErrTransport, got <%v>", err)
+	}
+
+	var te *TransportError
+	if !errors.As(err, &te) || te.Op != "validate ticket" {
+		t.Errorf("Expected TransportError for validate ticket, got <%#v>", err)
+	}
+}
+
+func TestRestClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cas/v1/tickets":
+			if r.FormValue("username") == "nolocation" {
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	serviceURL, _ := url.Parse("https://service.example.com/")
+	restClient := NewRestClient(&RestOptions{
+		CasURL:     casURL,
+		ServiceURL: serviceURL,
+		Client:     server.Client(),
+	})
+
+	_, err := restClient.RequestGrantingTicket("arthur", "dent")
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected error matching ErrInvalidCredentials, got <%v>", err)
+	}
+
+	var ce *CredentialError
+	if !errors.As(err, &ce) || ce.Username != "arthur" || ce.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected CredentialError for arthur, got <%#v>", err)
+	}
+
+	_, err = restClient.RequestGrantingTicket("nolocation", "secret")
+	if !errors.Is(err, ErrMalformedResponse) {
+		t.Errorf("Expected error matching ErrMalformedResponse, got <%v>", err)
+	}
+
+	_, err = restClient.RequestServiceTicket("TGT-abc")
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected StatusError with status 500, got <%v>", err)
+	}
+
+	if err := restClient.Logout("TGT-abc"); !errors.Is(err, ErrUnexpectedStatus) {
+		t.Errorf("Expected error matching ErrUnexpectedStatus, got <%v>", err)
+	}
+}
+
+func TestStatusErrorTruncatesBody(t *testing.T) {
+	err := &StatusError{Op: "validate ticket", StatusCode: http.StatusBadGateway, Body: strings.Repeat("x", 1<<20)}
+
+	if n := len(err.Error()); n > 256 {
+		t.Errorf("Expected error message to be shortened, got %d bytes", n)
+	}
+}
@@ -0,0 +1,90 @@
+package cas
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// flightGroup coalesces concurrent calls sharing a key into a single execution
+// whose result is handed to every caller.
+type flightGroup struct {
+	timeout time.Duration // Bounds the shared execution, unbounded if zero
+
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is an in-flight or completed flightGroup call
+type flightCall struct {
+	done chan struct{}
+	err  error
+}
+
+// do executes fn unless a call for key is already in flight, in which case it
+// waits for that call and returns its error. shared reports whether the result
+// came from another caller.
+//
+// fn runs on a context carrying the values of ctx but detached from its
+// cancellation, so a caller going away does not fail the callers waiting on
+// the same key. Each caller stops waiting early when its own ctx is done.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) error) (shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	c, shared := g.calls[key]
+	if !shared {
+		c = &flightCall{done: make(chan struct{})}
+		g.calls[key] = c
+
+		go g.run(detachedContext{ctx}, key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return shared, c.err
+	case <-ctx.Done():
+		return shared, ctx.Err()
+	}
+}
+
+// run executes fn for the call and releases its waiters
+func (g *flightGroup) run(ctx context.Context, key string, c *flightCall, fn func(ctx context.Context) error) {
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("cas: panic in coalesced call: %v", r)
+		}
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+
+		close(c.done)
+	}()
+
+	c.err = fn(ctx)
+}
+
+// detachedContext carries the values of its parent, but not its deadline or
+// cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,142 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroupCoalescesCalls(t *testing.T) {
+	var g flightGroup
+	var calls int32
+	release := make(chan struct{})
+	errDone := errors.New("done")
+
+	fn := func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return errDone
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan bool, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			shared, err := g.do(context.Background(), "ST-1", fn)
+			if err != errDone {
+				t.Errorf("Expected error of the shared call, got %v", err)
+			}
+			results <- shared
+		}()
+	}
+
+	for {
+		g.mu.Lock()
+		c := g.calls["ST-1"]
+		g.mu.Unlock()
+		if c != nil && atomic.LoadInt32(&calls) == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// let the other callers join the call in flight
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	owners := 0
+	for shared := range results {
+		if !shared {
+			owners++
+		}
+	}
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected 1 call, got %d", n)
+	}
+
+	if owners != 1 {
+		t.Errorf("Expected exactly 1 caller to own the call, got %d", owners)
+	}
+
+	if _, err := g.do(context.Background(), "ST-1", func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("Expected completed call to be forgotten, got %v", err)
+	}
+}
+
+func TestFlightGroupCallerCancellation(t *testing.T) {
+	var g flightGroup
+	release := make(chan struct{})
+	finished := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := g.do(ctx, "ST-1", func(ctx context.Context) error {
+		<-release
+		finished <- ctx.Err()
+		return nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancelled caller to stop waiting, got %v", err)
+	}
+
+	close(release)
+	if err := <-finished; err != nil {
+		t.Errorf("Expected shared call to outlive the cancelled caller, got %v", err)
+	}
+}
+
+func TestFlightGroupTimeout(t *testing.T) {
+	g := flightGroup{timeout: time.Millisecond}
+
+	_, err := g.do(context.Background(), "ST-1", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected shared call to be bounded by the timeout, got %v", err)
+	}
+}
+
+func TestFlightGroupRecoversPanic(t *testing.T) {
+	var g flightGroup
+
+	_, err := g.do(context.Background(), "ST-1", func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	if err == nil {
+		t.Errorf("Expected panic to be reported as an error")
+	}
+}
+
+func TestDetachedContext(t *testing.T) {
+	type key struct{}
+
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "value"), time.Hour)
+	cancel()
+
+	ctx := detachedContext{parent}
+
+	if _, ok := ctx.Deadline(); ok {
+		t.Errorf("Expected no deadline")
+	}
+
+	if ctx.Done() != nil || ctx.Err() != nil {
+		t.Errorf("Expected cancellation of the parent to be ignored, got %v", ctx.Err())
+	}
+
+	if v := ctx.Value(key{}); v != "value" {
+		t.Errorf("Expected value of the parent, got %v", v)
+	}
+}
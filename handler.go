@@ -25,13 +25,24 @@ func (ch *clientHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	setClient(r, ch.c)
+	setPrivateKey(r, ch.c.privateKey)
 
 	if isSingleLogoutRequest(r) {
 		ch.performSingleLogout(w, r)
 		return
 	}
 
-	ch.c.getSession(w, r)
+	if ticket := ch.c.getSession(w, r); ticket != "" {
+		if err := ch.c.resolvePrincipal(r, ticket); err != nil {
+			if glog.V(1) {
+				glog.Infof("cas: resolving principal for %v failed: %v", Username(r), err)
+			}
+
+			ch.c.principalErrorHandler(w, r, err)
+			return
+		}
+	}
+
 	ch.h.ServeHTTP(w, r)
 	return
 }
@@ -66,12 +77,14 @@ func (ch *clientHandler) performSingleLogout(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := ch.c.tickets.Delete(logoutRequest.SessionIndex); err != nil {
+	ctx := r.Context()
+	if err := ch.c.tickets.DeleteContext(ctx, logoutRequest.SessionIndex); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	ch.c.deleteSession(logoutRequest.SessionIndex)
+	ch.c.deleteSession(ctx, logoutRequest.SessionIndex)
+	ch.c.invalidatePrincipal(logoutRequest.SessionIndex)
 
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintln(w, "OK")
@@ -0,0 +1,178 @@
+package cas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Health check probe values. The ticket is deliberately invalid, a cas server
+// able to validate tickets answers with an INVALID_TICKET failure.
+const (
+	healthCheckTicket  = "ST-cas-health-check"
+	healthCheckService = "https://cas-health-check.invalid/"
+)
+
+// Health status values
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthReport describes the reachability of the cas servers
+type HealthReport struct {
+	Status         string           `json:"status"`                    // HealthStatusOK if any cas server is able to validate tickets
+	Latency        time.Duration    `json:"latency_ns"`                // Time taken by the health check
+	LastError      string           `json:"last_error,omitempty"`      // Last error encountered probing the cas servers
+	CircuitBreaker string           `json:"circuit_breaker,omitempty"` // State of the circuit breaker, if configured
+	Endpoints      []EndpointHealth `json:"endpoints"`                 // Result of probing each cas server
+}
+
+// EndpointHealth describes the reachability of a single cas server
+type EndpointHealth struct {
+	URL     string        `json:"url"`             // Service validate url of the cas server
+	Healthy bool          `json:"healthy"`         // Whether the cas server answered the probe
+	Latency time.Duration `json:"latency_ns"`      // Time taken to answer the probe
+	Error   string        `json:"error,omitempty"` // Reason the probe failed
+}
+
+// Healthy reports whether any cas server is able to validate tickets
+func (r *HealthReport) Healthy() bool {
+	return r.Status == HealthStatusOK
+}
+
+// HealthCheck probes each configured cas server by validating a deliberately
+// invalid ticket. A server answering with INVALID_TICKET is healthy.
+//
+// The probes bypass the retry policy and circuit breaker. An error is returned
+// with the report if no cas server is healthy.
+func (c *Client) HealthCheck(ctx context.Context) (*HealthReport, error) {
+	return c.stValidator.healthCheck(ctx)
+}
+
+// healthCheck probes every endpoint of the validator.
+func (validator *ServiceTicketValidator) healthCheck(ctx context.Context) (*HealthReport, error) {
+	service, _ := url.Parse(healthCheckService)
+	start := validator.clock.Now()
+
+	report := &HealthReport{Status: HealthStatusUnavailable}
+	if b := validator.CircuitBreaker; b != nil {
+		report.CircuitBreaker = b.State().String()
+	}
+
+	var lastErr error
+	for _, e := range validator.endpoints.all() {
+		probeStart := validator.clock.Now()
+		err := healthCheckResult(validator.validateTicket(ctx, e.scheme, service, healthCheckTicket))
+
+		h := EndpointHealth{Healthy: err == nil, Latency: validator.clock.Now().Sub(probeStart)}
+		if u, uerr := e.scheme.ServiceValidate(); uerr == nil {
+			h.URL = u.String()
+		}
+
+		if err != nil {
+			h.Error = err.Error()
+			lastErr = err
+		} else {
+			report.Status = HealthStatusOK
+		}
+
+		if glog.V(2) {
+			glog.Infof("Health check of %v: healthy %v, latency %v, error %v", h.URL, h.Healthy, h.Latency, err)
+		}
+
+		report.Endpoints = append(report.Endpoints, h)
+	}
+
+	report.Latency = validator.clock.Now().Sub(start)
+	if lastErr != nil {
+		report.LastError = lastErr.Error()
+	}
+
+	if !report.Healthy() {
+		return report, lastErr
+	}
+
+	return report, nil
+}
+
+// errUnexpectedHealthCheckSuccess reports a cas server accepting the probe ticket
+var errUnexpectedHealthCheckSuccess = errors.New("cas: health check: probe ticket unexpectedly validated")
+
+// healthCheckResult interprets the validation of the probe ticket.
+func healthCheckResult(_ *AuthenticationResponse, err error) error {
+	if err == nil {
+		return errUnexpectedHealthCheckSuccess
+	}
+
+	var authErr *AuthenticationError
+	if errors.As(err, &authErr) && (authErr.Code == INVALID_TICKET || authErr.Code == INVALID_TICKET_SPEC) {
+		return nil
+	}
+
+	return err
+}
+
+// healthHandlerTTL is the time HealthHandler answers from the last report
+// before probing the cas servers again.
+const healthHandlerTTL = 5 * time.Second
+
+// healthSummary is the part of a HealthReport exposed by HealthHandler
+type healthSummary struct {
+	Status  string        `json:"status"`
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// HealthHandler returns a http.Handler reporting the status and latency of
+// HealthCheck as JSON, with status 200 if healthy and 503 otherwise. Use
+// HealthCheck for the details of each cas server, the handler does not expose
+// them.
+//
+// The report is reused for a few seconds, so frequent probes of the handler
+// do not load the cas servers.
+func (c *Client) HealthHandler() http.Handler {
+	var (
+		mu      sync.Mutex
+		summary healthSummary
+		expires time.Time
+	)
+
+	check := func(ctx context.Context) healthSummary {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if c.stValidator.clock.Now().Before(expires) {
+			return summary
+		}
+
+		report, _ := c.HealthCheck(ctx)
+		summary = healthSummary{Status: report.Status, Latency: report.Latency}
+		expires = c.stValidator.clock.Now().Add(healthHandlerTTL)
+		return summary
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		summary := check(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+
+		if summary.Status == HealthStatusOK {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		if err := json.NewEncoder(w).Encode(summary); err != nil {
+			if glog.V(1) {
+				glog.Infof("Error writing health report: %v", err)
+			}
+		}
+	})
+}
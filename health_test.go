@@ -0,0 +1,152 @@
+package cas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const invalidTicketResponse = `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationFailure code="INVALID_TICKET">Ticket not recognized</cas:authenticationFailure>
+</cas:serviceResponse>`
+
+func TestHealthCheck(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("ticket") != healthCheckTicket {
+			t.Errorf("Expected probe ticket, got %q", r.URL.Query().Get("ticket"))
+		}
+
+		w.Write([]byte(invalidTicketResponse))
+	}))
+	defer healthy.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer broken.Close()
+
+	casURL, _ := url.Parse(broken.URL + "/cas/")
+	healthyURL, _ := url.Parse(healthy.URL + "/cas/")
+
+	breaker := NewCircuitBreaker(nil)
+	client := NewClient(&Options{
+		URL:            casURL,
+		ValidationURLs: []*url.URL{casURL, healthyURL},
+		CircuitBreaker: breaker,
+	})
+
+	report, err := client.HealthCheck(context.Background())
+	if err != nil {
+		t.Fatalf("Expected healthy report, got %v", err)
+	}
+
+	if report.Status != HealthStatusOK || report.CircuitBreaker != "closed" {
+		t.Errorf("Unexpected report %#v", report)
+	}
+
+	if len(report.Endpoints) != 2 || report.Endpoints[0].Healthy || !report.Endpoints[1].Healthy {
+		t.Errorf("Unexpected endpoints %#v", report.Endpoints)
+	}
+
+	if report.LastError == "" {
+		t.Errorf("Expected last error of broken endpoint to be reported")
+	}
+
+	if stats := breaker.Stats(); stats.Failures != 0 {
+		t.Errorf("Expected health check to bypass circuit breaker, got %d failures", stats.Failures)
+	}
+}
+
+func TestHealthCheckLatency(t *testing.T) {
+	clock := newTestClock()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clock.Advance(250 * time.Millisecond)
+		w.Write([]byte(invalidTicketResponse))
+	}))
+	defer server.Close()
+
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	client := NewClient(&Options{
+		URL:            casURL,
+		ValidationURLs: []*url.URL{casURL, casURL},
+		Clock:          clock,
+	})
+
+	report, err := client.HealthCheck(context.Background())
+	if err != nil {
+		t.Fatalf("Expected healthy report, got %v", err)
+	}
+
+	if report.Latency != 500*time.Millisecond {
+		t.Errorf("Expected latency of 500ms, got %v", report.Latency)
+	}
+
+	for _, e := range report.Endpoints {
+		if e.Latency != 250*time.Millisecond {
+			t.Errorf("Expected endpoint latency of 250ms, got %v", e.Latency)
+		}
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	var probes int32
+	status := int32(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		if code := atomic.LoadInt32(&status); code != http.StatusOK {
+			w.WriteHeader(int(code))
+			return
+		}
+
+		w.Write([]byte(invalidTicketResponse))
+	}))
+	defer server.Close()
+
+	clock := newTestClock()
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	client := NewClient(&Options{URL: casURL, Clock: clock})
+	handler := client.HealthHandler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	var report map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Expected JSON report, got %v", err)
+	}
+
+	if report["status"] != HealthStatusOK || len(report) != 2 {
+		t.Errorf("Expected only status and latency to be reported, got %v", report)
+	}
+
+	atomic.StoreInt32(&status, http.StatusInternalServerError)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected cached status 200, got %d", w.Code)
+	}
+
+	if n := atomic.LoadInt32(&probes); n != 1 {
+		t.Errorf("Expected 1 probe of the cas server, got %d", n)
+	}
+
+	clock.Advance(healthHandlerTTL)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+}
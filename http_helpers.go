@@ -2,6 +2,7 @@ package cas
 
 import (
 	"context"
+	"crypto"
 	"net/http"
 	"time"
 )
@@ -11,6 +12,8 @@ type key int
 const ( // emulating enums is actually pretty ugly in go.
 	clientKey key = iota
 	authenticationResponseKey
+	principalKey
+	privateKeyKey
 )
 
 // setClient associates a Client with a http.Request.
@@ -29,6 +32,26 @@ func getClient(r *http.Request) *Client {
 	return nil // explicitly pass along the nil to caller -- conforms to previous impl
 }
 
+// setPrivateKey associates the private key of the service with a http.Request.
+func setPrivateKey(r *http.Request, k crypto.Decrypter) {
+	if k == nil {
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), privateKeyKey, k)
+	r2 := r.WithContext(ctx)
+	*r = *r2
+}
+
+// getPrivateKey retrieves the private key of the service associated with the http.Request.
+func getPrivateKey(r *http.Request) crypto.Decrypter {
+	if k := r.Context().Value(privateKeyKey); k != nil {
+		return k.(crypto.Decrypter)
+	}
+
+	return nil
+}
+
 // RedirectToLogin allows CAS protected handlers to redirect a request
 // to the CAS login page.
 func RedirectToLogin(w http.ResponseWriter, r *http.Request) {
@@ -82,6 +105,12 @@ func IsAuthenticated(r *http.Request) bool {
 	return false
 }
 
+// Authentication returns the AuthenticationResponse of the authenticated user,
+// or nil if the request is not authenticated.
+func Authentication(r *http.Request) *AuthenticationResponse {
+	return getAuthenticationResponse(r)
+}
+
 // Username returns the authenticated users username
 func Username(r *http.Request) string {
 	if a := getAuthenticationResponse(r); a != nil {
@@ -0,0 +1,126 @@
+package cas
+
+import (
+	"sync"
+	"time"
+)
+
+// localCache is a small in-process cache whose entries expire after a fixed ttl.
+// Expired entries are swept on set, at most once per ttl.
+type localCache struct {
+	mu         sync.Mutex
+	clock      Clock
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]localCacheEntry
+	nextSweep  time.Time
+}
+
+type localCacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+func newLocalCache(clock Clock, ttl time.Duration, maxEntries int) *localCache {
+	return &localCache{
+		clock:      clockOrDefault(clock),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]localCacheEntry),
+	}
+}
+
+// get returns the cached value for key, if present and not yet expired.
+func (c *localCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !c.clock.Now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+// set caches value under key for the ttl of the cache.
+func (c *localCache) set(key string, value interface{}) {
+	c.setTTL(key, value, c.ttl)
+}
+
+// setTTL caches value under key for the given ttl.
+func (c *localCache) setTTL(key string, value interface{}, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !now.Before(c.nextSweep) {
+		c.sweep(now)
+	}
+
+	if _, ok := c.entries[key]; !ok && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+
+	c.entries[key] = localCacheEntry{value: value, expires: now.Add(ttl)}
+}
+
+// sweep drops expired entries. Must be called with c.mu held.
+func (c *localCache) sweep(now time.Time) {
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.nextSweep = now.Add(c.ttl)
+}
+
+// evict drops expired entries, and an arbitrary entry if the cache is still
+// full. Must be called with c.mu held.
+func (c *localCache) evict(now time.Time) {
+	c.sweep(now)
+
+	for k := range c.entries {
+		if len(c.entries) < c.maxEntries {
+			break
+		}
+
+		delete(c.entries, k)
+	}
+}
+
+// delete removes key from the cache.
+func (c *localCache) delete(key string) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
+
+// deleteFunc removes every entry for which fn returns true.
+func (c *localCache) deleteFunc(fn func(key string, value interface{}) bool) {
+	c.mu.Lock()
+	for k, e := range c.entries {
+		if fn(k, e.value) {
+			delete(c.entries, k)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// purge removes all entries from the cache.
+func (c *localCache) purge() {
+	c.mu.Lock()
+	c.entries = make(map[string]localCacheEntry)
+	c.mu.Unlock()
+}
@@ -0,0 +1,25 @@
+package cas
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocalCacheSweepsExpiredEntries(t *testing.T) {
+	clock := newTestClock()
+	cache := newLocalCache(clock, time.Minute, 0)
+
+	cache.set("ST-1", "a")
+	cache.set("ST-2", "b")
+
+	clock.Advance(time.Minute)
+	cache.set("ST-3", "c")
+
+	if n := len(cache.entries); n != 1 {
+		t.Errorf("Expected expired entries to be swept leaving 1 entry, got %v", n)
+	}
+
+	if v, ok := cache.get("ST-3"); !ok || v != "c" {
+		t.Errorf("Expected ST-3 to be cached, got %v, %v", v, ok)
+	}
+}
@@ -1,8 +1,8 @@
 package cas
 
 import (
-	"crypto/rand"
 	"encoding/xml"
+	"io"
 	"strings"
 	"time"
 )
@@ -48,25 +48,32 @@ func parseDate(raw string) (time.Time, error) {
 	return t, nil
 }
 
-func newLogoutRequestID() string {
+func newLogoutRequestID(entropy io.Reader) (string, error) {
 	const alphabet = "abcdef0123456789"
 
 	// generate 64 character string
 	bytes := make([]byte, 64)
-	rand.Read(bytes)
+	if _, err := io.ReadFull(entropy, bytes); err != nil {
+		return "", err
+	}
 
 	for k, v := range bytes {
 		bytes[k] = alphabet[v%byte(len(alphabet))]
 	}
 
-	return string(bytes)
+	return string(bytes), nil
 }
 
-func xmlLogoutRequest(ticket string) ([]byte, error) {
+func xmlLogoutRequest(ticket string, clock Clock, entropy io.Reader) ([]byte, error) {
+	id, err := newLogoutRequestID(entropy)
+	if err != nil {
+		return nil, err
+	}
+
 	l := &logoutRequest{
 		Version:      "2.0",
-		IssueInstant: time.Now().UTC(),
-		ID:           newLogoutRequestID(),
+		IssueInstant: clock.Now().UTC(),
+		ID:           id,
 		NameID:       "@NOT_USED@",
 		SessionIndex: ticket,
 	}
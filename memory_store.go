@@ -1,13 +1,23 @@
 package cas
 
 import (
+	"io"
 	"sync"
+	"time"
 )
 
 // MemoryStore implements the TicketStore interface storing ticket data in memory.
 type MemoryStore struct {
+	Clock Clock // Custom Clock used to timestamp entries, if nil the system time is used
+
 	mu    sync.RWMutex
-	store map[string]*AuthenticationResponse
+	store map[string]*memoryTicket
+}
+
+// memoryTicket is a MemoryStore entry
+type memoryTicket struct {
+	response *AuthenticationResponse
+	written  time.Time
 }
 
 // Read returns the AuthenticationResponse for a ticket
@@ -26,7 +36,7 @@ func (s *MemoryStore) Read(id string) (*AuthenticationResponse, error) {
 		return nil, ErrInvalidTicket
 	}
 
-	return t, nil
+	return t.response, nil
 }
 
 // Write stores the AuthenticationResponse for a ticket
@@ -34,10 +44,10 @@ func (s *MemoryStore) Write(id string, ticket *AuthenticationResponse) error {
 	s.mu.Lock()
 
 	if s.store == nil {
-		s.store = make(map[string]*AuthenticationResponse)
+		s.store = make(map[string]*memoryTicket)
 	}
 
-	s.store[id] = ticket
+	s.store[id] = &memoryTicket{response: ticket, written: clockOrDefault(s.Clock).Now()}
 
 	s.mu.Unlock()
 	return nil
@@ -58,3 +68,47 @@ func (s *MemoryStore) Clear() error {
 	s.mu.Unlock()
 	return nil
 }
+
+// Snapshot writes all ticket data to w
+func (s *MemoryStore) Snapshot(w io.Writer) error {
+	snap := &snapshot{Kind: ticketSnapshotKind}
+
+	s.mu.RLock()
+	for id, t := range s.store {
+		snap.Tickets = append(snap.Tickets, ticketEntry{
+			ID:       id,
+			Written:  t.written,
+			Response: t.response,
+		})
+	}
+	s.mu.RUnlock()
+
+	return writeSnapshot(w, snap)
+}
+
+// Restore reads ticket data written by Snapshot, skipping tickets older than maxAge
+func (s *MemoryStore) Restore(r io.Reader, maxAge time.Duration) error {
+	snap, err := readSnapshot(r, ticketSnapshotKind)
+	if err != nil {
+		return err
+	}
+
+	now := clockOrDefault(s.Clock).Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.store == nil {
+		s.store = make(map[string]*memoryTicket)
+	}
+
+	for _, e := range snap.Tickets {
+		if e.Response == nil || expired(e.Written, now, maxAge) {
+			continue
+		}
+
+		s.store[e.ID] = &memoryTicket{response: e.Response, written: e.Written}
+	}
+
+	return nil
+}
@@ -8,6 +8,9 @@ import (
 
 // Handler returns a standard http.HandlerFunc, which will check the authenticated status (redirect user go login if needed)
 // If the user pass the authenticated check, it will call the h's ServeHTTP method
+//
+// Requests are protected according to the Routes, LogoutPath, LocalLogoutPath
+// and BypassMethods options of the Client.
 func (c *Client) Handler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if glog.V(2) {
@@ -15,16 +18,36 @@ func (c *Client) Handler(h http.Handler) http.Handler {
 		}
 
 		setClient(r, c)
+		setPrivateKey(r, c.privateKey)
 
-		if !IsAuthenticated(r) {
-			RedirectToLogin(w, r)
+		if c.bypassesAuth(r.Method) {
+			h.ServeHTTP(w, r)
 			return
 		}
 
-		if r.URL.Path == "/logout" {
+		if samePath(r.URL.Path, c.logoutPath) {
 			RedirectToLogout(w, r)
 			return
 		}
+
+		if c.localLogoutPath != "" && samePath(r.URL.Path, c.localLogoutPath) {
+			c.logoutLocally(w, r)
+			return
+		}
+
+		switch c.authMode(r.URL.Path) {
+		case AuthRequired:
+			if !IsAuthenticated(r) {
+				RedirectToLogin(w, r)
+				return
+			}
+		case AuthOptional:
+			if !IsAuthenticated(r) && !gatewayAttempted(r) {
+				c.redirectToGateway(w, r)
+				return
+			}
+		}
+
 		h.ServeHTTP(w, r)
 	})
 }
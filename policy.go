@@ -0,0 +1,591 @@
+package cas
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Policy is a compiled authorization expression over an AuthenticationResponse.
+//
+// Expressions combine the identifiers
+//
+//	user             string   the users login name
+//	memberOf         list     groups the user is a member of
+//	proxies          list     proxies the ticket was passed through
+//	newLogin         bool     whether the ticket was granted by a new login
+//	rememberedLogin  bool     whether the ticket was granted by a long term token
+//	impersonated     bool     whether the user is impersonated by surrogate authentication
+//	realUser         string   login name of the person who authenticated
+//	attr.<name>      list     values of the named user attribute
+//
+// with string literals ("ops"), list literals (["a", "b"]), true and false, the
+// operators ==, !=, in, !, && and ||, and parentheses. Comparing a list with a
+// string using == is satisfied if any element of the list is equal, in checks
+// for a string in a list. For example:
+//
+//	"admins" in memberOf && attr.department == "ops" && newLogin
+type Policy struct {
+	src  string
+	eval func(a *AuthenticationResponse) policyValue
+}
+
+// PolicyError reports an invalid policy expression
+type PolicyError struct {
+	Expr string // Expression being compiled
+	Pos  int    // Byte offset of the error in Expr
+	Msg  string // Description of the error
+}
+
+// Error returns the PolicyError as a string
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("cas: policy: column %d: %s", e.Pos+1, e.Msg)
+}
+
+// CompilePolicy parses a policy expression
+func CompilePolicy(expr string) (*Policy, error) {
+	p := &policyParser{lex: policyLexer{src: expr}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != policyEOF {
+		return nil, p.errorf(p.tok.pos, "unexpected %s", p.tok)
+	}
+
+	if n.typ != policyBool {
+		return nil, p.errorf(0, "expression is %s, not bool", n.typ)
+	}
+
+	return &Policy{src: expr, eval: n.eval}, nil
+}
+
+// MustCompilePolicy is like CompilePolicy but panics if the expression is invalid
+func MustCompilePolicy(expr string) *Policy {
+	p, err := CompilePolicy(expr)
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// Evaluate reports whether the AuthenticationResponse satisfies the policy.
+// A nil or zero Policy is never satisfied.
+func (p *Policy) Evaluate(a *AuthenticationResponse) bool {
+	if p == nil || p.eval == nil || a == nil {
+		return false
+	}
+
+	return p.eval(a).b
+}
+
+// Rule returns the policy as a Rule for use with Require
+func (p *Policy) Rule() Rule {
+	return p.Evaluate
+}
+
+// String returns the source of the policy expression
+func (p *Policy) String() string {
+	return p.src
+}
+
+// MarshalText returns the source of the policy expression
+func (p *Policy) MarshalText() ([]byte, error) {
+	return []byte(p.src), nil
+}
+
+// UnmarshalText compiles the policy expression, allowing policies to be read
+// from configuration files.
+func (p *Policy) UnmarshalText(text []byte) error {
+	compiled, err := CompilePolicy(string(text))
+	if err != nil {
+		return err
+	}
+
+	*p = *compiled
+	return nil
+}
+
+// RequirePolicy returns Middleware allowing access to users satisfying the policy
+func RequirePolicy(p *Policy) Middleware {
+	return Require(p.Rule())
+}
+
+// policyType is the type of a policy expression
+type policyType int
+
+const (
+	policyBool policyType = iota
+	policyString
+	policyList
+)
+
+func (t policyType) String() string {
+	switch t {
+	case policyBool:
+		return "bool"
+	case policyString:
+		return "string"
+	default:
+		return "list"
+	}
+}
+
+// policyValue is the result of evaluating a policy expression
+type policyValue struct {
+	b bool
+	s string
+	l []string
+}
+
+// policyNode is a type checked policy expression
+type policyNode struct {
+	typ  policyType
+	eval func(a *AuthenticationResponse) policyValue
+}
+
+// policyIdents are the identifiers available in policy expressions
+var policyIdents = map[string]policyNode{
+	"user": {policyString, func(a *AuthenticationResponse) policyValue {
+		return policyValue{s: a.User}
+	}},
+	"memberOf": {policyList, func(a *AuthenticationResponse) policyValue {
+		return policyValue{l: a.MemberOf}
+	}},
+	"proxies": {policyList, func(a *AuthenticationResponse) policyValue {
+		return policyValue{l: a.Proxies}
+	}},
+	"newLogin": {policyBool, func(a *AuthenticationResponse) policyValue {
+		return policyValue{b: a.IsNewLogin}
+	}},
+	"rememberedLogin": {policyBool, func(a *AuthenticationResponse) policyValue {
+		return policyValue{b: a.IsRememberedLogin}
+	}},
+	"impersonated": {policyBool, func(a *AuthenticationResponse) policyValue {
+		return policyValue{b: a.IsImpersonated()}
+	}},
+	"realUser": {policyString, func(a *AuthenticationResponse) policyValue {
+		return policyValue{s: a.RealUsername()}
+	}},
+}
+
+// policyParser is a recursive descent parser for policy expressions
+type policyParser struct {
+	lex policyLexer
+	tok policyToken
+}
+
+func (p *policyParser) next() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+
+	p.tok = tok
+	return nil
+}
+
+func (p *policyParser) errorf(pos int, format string, args ...interface{}) error {
+	return &PolicyError{Expr: p.lex.src, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// expectBool checks the operand of a boolean operator
+func (p *policyParser) expectBool(n policyNode, pos int, op string) error {
+	if n.typ != policyBool {
+		return p.errorf(pos, "operand of %s is %s, not bool", op, n.typ)
+	}
+
+	return nil
+}
+
+// parseOr parses and ('||' and)*
+func (p *policyParser) parseOr() (policyNode, error) {
+	pos := p.tok.pos
+	left, err := p.parseAnd()
+	if err != nil {
+		return left, err
+	}
+
+	for p.tok.kind == policyOr {
+		if err := p.next(); err != nil {
+			return left, err
+		}
+
+		rpos := p.tok.pos
+		right, err := p.parseAnd()
+		if err != nil {
+			return right, err
+		}
+
+		if err := p.expectBool(left, pos, "||"); err != nil {
+			return left, err
+		}
+
+		if err := p.expectBool(right, rpos, "||"); err != nil {
+			return right, err
+		}
+
+		l, r := left.eval, right.eval
+		left = policyNode{policyBool, func(a *AuthenticationResponse) policyValue {
+			return policyValue{b: l(a).b || r(a).b}
+		}}
+	}
+
+	return left, nil
+}
+
+// parseAnd parses unary ('&&' unary)*
+func (p *policyParser) parseAnd() (policyNode, error) {
+	pos := p.tok.pos
+	left, err := p.parseUnary()
+	if err != nil {
+		return left, err
+	}
+
+	for p.tok.kind == policyAnd {
+		if err := p.next(); err != nil {
+			return left, err
+		}
+
+		rpos := p.tok.pos
+		right, err := p.parseUnary()
+		if err != nil {
+			return right, err
+		}
+
+		if err := p.expectBool(left, pos, "&&"); err != nil {
+			return left, err
+		}
+
+		if err := p.expectBool(right, rpos, "&&"); err != nil {
+			return right, err
+		}
+
+		l, r := left.eval, right.eval
+		left = policyNode{policyBool, func(a *AuthenticationResponse) policyValue {
+			return policyValue{b: l(a).b && r(a).b}
+		}}
+	}
+
+	return left, nil
+}
+
+// parseUnary parses '!' unary | comparison
+func (p *policyParser) parseUnary() (policyNode, error) {
+	if p.tok.kind != policyNot {
+		return p.parseComparison()
+	}
+
+	if err := p.next(); err != nil {
+		return policyNode{}, err
+	}
+
+	pos := p.tok.pos
+	n, err := p.parseUnary()
+	if err != nil {
+		return n, err
+	}
+
+	if err := p.expectBool(n, pos, "!"); err != nil {
+		return n, err
+	}
+
+	eval := n.eval
+	return policyNode{policyBool, func(a *AuthenticationResponse) policyValue {
+		return policyValue{b: !eval(a).b}
+	}}, nil
+}
+
+// parseComparison parses operand (('==' | '!=' | 'in') operand)?
+func (p *policyParser) parseComparison() (policyNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return left, err
+	}
+
+	op := p.tok
+	switch op.kind {
+	case policyEq, policyNeq, policyIn:
+	default:
+		return left, nil
+	}
+
+	if err := p.next(); err != nil {
+		return left, err
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return right, err
+	}
+
+	l, r := left.eval, right.eval
+
+	if op.kind == policyIn {
+		if left.typ != policyString || right.typ != policyList {
+			return left, p.errorf(op.pos, "in requires string and list operands, got %s and %s", left.typ, right.typ)
+		}
+
+		return policyNode{policyBool, func(a *AuthenticationResponse) policyValue {
+			return policyValue{b: containsAny(r(a).l, []string{l(a).s})}
+		}}, nil
+	}
+
+	var eq func(a *AuthenticationResponse) bool
+	switch {
+	case left.typ == policyBool && right.typ == policyBool:
+		eq = func(a *AuthenticationResponse) bool { return l(a).b == r(a).b }
+	case left.typ == policyString && right.typ == policyString:
+		eq = func(a *AuthenticationResponse) bool { return l(a).s == r(a).s }
+	case left.typ == policyList && right.typ == policyString:
+		eq = func(a *AuthenticationResponse) bool { return containsAny(l(a).l, []string{r(a).s}) }
+	case left.typ == policyString && right.typ == policyList:
+		eq = func(a *AuthenticationResponse) bool { return containsAny(r(a).l, []string{l(a).s}) }
+	default:
+		return left, p.errorf(op.pos, "can not compare %s with %s", left.typ, right.typ)
+	}
+
+	negate := op.kind == policyNeq
+	return policyNode{policyBool, func(a *AuthenticationResponse) policyValue {
+		return policyValue{b: eq(a) != negate}
+	}}, nil
+}
+
+// parseOperand parses string | list | true | false | ident | '(' or ')'
+func (p *policyParser) parseOperand() (policyNode, error) {
+	tok := p.tok
+
+	switch tok.kind {
+	case policyStringLit:
+		if err := p.next(); err != nil {
+			return policyNode{}, err
+		}
+
+		s := tok.text
+		return policyNode{policyString, func(*AuthenticationResponse) policyValue {
+			return policyValue{s: s}
+		}}, nil
+
+	case policyLBracket:
+		return p.parseList()
+
+	case policyLParen:
+		if err := p.next(); err != nil {
+			return policyNode{}, err
+		}
+
+		n, err := p.parseOr()
+		if err != nil {
+			return n, err
+		}
+
+		if p.tok.kind != policyRParen {
+			return n, p.errorf(p.tok.pos, "expected ), got %s", p.tok)
+		}
+
+		return n, p.next()
+
+	case policyIdent:
+		if err := p.next(); err != nil {
+			return policyNode{}, err
+		}
+
+		return p.ident(tok)
+	}
+
+	return policyNode{}, p.errorf(tok.pos, "unexpected %s", tok)
+}
+
+// parseList parses '[' (string (',' string)*)? ']'
+func (p *policyParser) parseList() (policyNode, error) {
+	var values []string
+
+	for {
+		if err := p.next(); err != nil {
+			return policyNode{}, err
+		}
+
+		if p.tok.kind == policyRBracket && len(values) == 0 {
+			break
+		}
+
+		if p.tok.kind != policyStringLit {
+			return policyNode{}, p.errorf(p.tok.pos, "expected string in list, got %s", p.tok)
+		}
+
+		values = append(values, p.tok.text)
+
+		if err := p.next(); err != nil {
+			return policyNode{}, err
+		}
+
+		if p.tok.kind == policyRBracket {
+			break
+		}
+
+		if p.tok.kind != policyComma {
+			return policyNode{}, p.errorf(p.tok.pos, "expected , or ], got %s", p.tok)
+		}
+	}
+
+	return policyNode{policyList, func(*AuthenticationResponse) policyValue {
+		return policyValue{l: values}
+	}}, p.next()
+}
+
+// ident resolves an identifier
+func (p *policyParser) ident(tok policyToken) (policyNode, error) {
+	switch tok.text {
+	case "true", "false":
+		b := tok.text == "true"
+		return policyNode{policyBool, func(*AuthenticationResponse) policyValue {
+			return policyValue{b: b}
+		}}, nil
+	}
+
+	if name := strings.TrimPrefix(tok.text, "attr."); name != tok.text {
+		if name == "" {
+			return policyNode{}, p.errorf(tok.pos, "missing attribute name after attr.")
+		}
+
+		return policyNode{policyList, func(a *AuthenticationResponse) policyValue {
+			return policyValue{l: a.Attributes[name]}
+		}}, nil
+	}
+
+	if n, ok := policyIdents[tok.text]; ok {
+		return n, nil
+	}
+
+	return policyNode{}, p.errorf(tok.pos, "unknown identifier %q", tok.text)
+}
+
+// policyTokenKind identifies the kind of a policyToken
+type policyTokenKind int
+
+const (
+	policyEOF policyTokenKind = iota
+	policyIdent
+	policyStringLit
+	policyLParen
+	policyRParen
+	policyLBracket
+	policyRBracket
+	policyComma
+	policyEq
+	policyNeq
+	policyIn
+	policyNot
+	policyAnd
+	policyOr
+)
+
+// policyToken is a lexical token of a policy expression
+type policyToken struct {
+	kind policyTokenKind
+	pos  int
+	text string
+}
+
+func (t policyToken) String() string {
+	switch t.kind {
+	case policyEOF:
+		return "end of expression"
+	case policyStringLit:
+		return strconv.Quote(t.text)
+	case policyIdent:
+		return t.text
+	}
+
+	return "'" + t.text + "'"
+}
+
+// policyLexer splits a policy expression into tokens
+type policyLexer struct {
+	src string
+	pos int
+}
+
+func (l *policyLexer) next() (policyToken, error) {
+	for l.pos < len(l.src) && (l.src[l.pos] == ' ' || l.src[l.pos] == '\t' || l.src[l.pos] == '\n' || l.src[l.pos] == '\r') {
+		l.pos++
+	}
+
+	start := l.pos
+	if start >= len(l.src) {
+		return policyToken{kind: policyEOF, pos: start}, nil
+	}
+
+	punct := []struct {
+		text string
+		kind policyTokenKind
+	}{
+		{"==", policyEq}, {"!=", policyNeq}, {"&&", policyAnd}, {"||", policyOr},
+		{"!", policyNot}, {"(", policyLParen}, {")", policyRParen},
+		{"[", policyLBracket}, {"]", policyRBracket}, {",", policyComma},
+	}
+
+	for _, p := range punct {
+		if strings.HasPrefix(l.src[start:], p.text) {
+			l.pos += len(p.text)
+			return policyToken{kind: p.kind, pos: start, text: p.text}, nil
+		}
+	}
+
+	if l.src[start] == '"' {
+		return l.string()
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.src[start:])
+	if !isPolicyIdentRune(r) {
+		return policyToken{}, &PolicyError{Expr: l.src, Pos: start, Msg: fmt.Sprintf("unexpected character %q", r)}
+	}
+
+	for l.pos < len(l.src) {
+		r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+		if !isPolicyIdentRune(r) {
+			break
+		}
+		l.pos += size
+	}
+
+	text := l.src[start:l.pos]
+	if text == "in" {
+		return policyToken{kind: policyIn, pos: start, text: text}, nil
+	}
+
+	return policyToken{kind: policyIdent, pos: start, text: text}, nil
+}
+
+// string scans a double quoted string literal with Go escapes
+func (l *policyLexer) string() (policyToken, error) {
+	start := l.pos
+	for i := start + 1; i < len(l.src); i++ {
+		switch l.src[i] {
+		case '\\':
+			i++
+		case '"':
+			s, err := strconv.Unquote(l.src[start : i+1])
+			if err != nil {
+				return policyToken{}, &PolicyError{Expr: l.src, Pos: start, Msg: "invalid string literal"}
+			}
+
+			l.pos = i + 1
+			return policyToken{kind: policyStringLit, pos: start, text: s}, nil
+		}
+	}
+
+	return policyToken{}, &PolicyError{Expr: l.src, Pos: start, Msg: "unterminated string literal"}
+}
+
+func isPolicyIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' || r == ':'
+}
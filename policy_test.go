@@ -0,0 +1,105 @@
+package cas
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPolicyEvaluate(t *testing.T) {
+	a := &AuthenticationResponse{
+		User:       "alice",
+		MemberOf:   []string{"staff", "admins"},
+		IsNewLogin: true,
+		Attributes: UserAttributes{"department": {"ops"}, "mail": {"alice@example.com"}},
+	}
+
+	tests := map[string]bool{
+		`"admins" in memberOf && attr.department == "ops" && newLogin`: true,
+		`"students" in memberOf`:     false,
+		`user == "alice"`:            true,
+		`user != "alice"`:            false,
+		`user in ["bob", "alice"]`:   true,
+		`!rememberedLogin`:           true,
+		`rememberedLogin == false`:   true,
+		`attr.department != "sales"`: true,
+		`attr.office == "London"`:    false,
+		`"ops" in attr.department`:   true,
+		`memberOf == "staff"`:        true,
+		`user == "bob" || ("staff" in memberOf && !(user == "eve"))`: true,
+		`proxies == "https://proxy.example.com/"`:                    false,
+		`true && !false`:              true,
+		`user == "a \"quoted\" name"`: false,
+	}
+
+	for expr, want := range tests {
+		p, err := CompilePolicy(expr)
+		if err != nil {
+			t.Errorf("CompilePolicy(%q) failed: %v", expr, err)
+			continue
+		}
+
+		if got := p.Evaluate(a); got != want {
+			t.Errorf("Evaluate(%q) = %v, want %v", expr, got, want)
+		}
+	}
+
+	if MustCompilePolicy("true").Evaluate(nil) {
+		t.Errorf("Expected policy to deny unauthenticated requests")
+	}
+
+	var nilPolicy *Policy
+	if nilPolicy.Evaluate(a) || new(Policy).Evaluate(a) {
+		t.Errorf("Expected nil and zero policies to deny access")
+	}
+}
+
+func TestCompilePolicyErrors(t *testing.T) {
+	tests := map[string]int{
+		``:                        0,
+		`user`:                    0,
+		`user == `:                8,
+		`"admins" in user`:        9,
+		`user == newLogin`:        5,
+		`unknown == "x"`:          0,
+		`newLogin && user`:        12,
+		`(newLogin`:               9,
+		`user == "unterminated`:   8,
+		`newLogin newLogin`:       9,
+		`user in ["a", newLogin]`: 14,
+		`attr. == "x"`:            0,
+		`user == "x" # comment`:   12,
+	}
+
+	for expr, pos := range tests {
+		_, err := CompilePolicy(expr)
+
+		var pe *PolicyError
+		if !errors.As(err, &pe) {
+			t.Errorf("CompilePolicy(%q): expected PolicyError, got %v", expr, err)
+			continue
+		}
+
+		if pe.Pos != pos {
+			t.Errorf("CompilePolicy(%q): expected error at %d, got %d (%v)", expr, pos, pe.Pos, err)
+		}
+	}
+}
+
+func TestPolicyUnmarshalText(t *testing.T) {
+	var config struct {
+		Admin *Policy `json:"admin"`
+	}
+
+	if err := json.Unmarshal([]byte(`{"admin": "\"admins\" in memberOf"}`), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if !config.Admin.Evaluate(&AuthenticationResponse{MemberOf: []string{"admins"}}) {
+		t.Errorf("Expected unmarshalled policy to allow admins")
+	}
+
+	if err := json.Unmarshal([]byte(`{"admin": "memberOf &&"}`), &config); err == nil {
+		t.Errorf("Expected invalid policy to fail to unmarshal")
+	}
+}
@@ -0,0 +1,89 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// DefaultPrincipalCacheTTL is the time a resolved principal is cached for when
+// the session cookie has no MaxAge.
+const DefaultPrincipalCacheTTL = 24 * time.Hour
+
+// DefaultPrincipalCacheSize is the number of resolved principals cached when
+// Options.PrincipalCacheSize is zero.
+const DefaultPrincipalCacheSize = 10000
+
+// ErrPrincipalNotFound is returned by a PrincipalResolver for users without an
+// application principal, e.g. users not provisioned locally.
+var ErrPrincipalNotFound = errors.New("cas: principal not found")
+
+// PrincipalResolver turns an authenticated user into the applications
+// representation of that user.
+type PrincipalResolver interface {
+	ResolvePrincipal(ctx context.Context, a *AuthenticationResponse) (interface{}, error)
+}
+
+// PrincipalResolverFunc adapts a function to a PrincipalResolver
+type PrincipalResolverFunc func(ctx context.Context, a *AuthenticationResponse) (interface{}, error)
+
+// ResolvePrincipal calls f(ctx, a)
+func (f PrincipalResolverFunc) ResolvePrincipal(ctx context.Context, a *AuthenticationResponse) (interface{}, error) {
+	return f(ctx, a)
+}
+
+// PrincipalErrorHandler responds to a request whose principal could not be resolved
+type PrincipalErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// DefaultPrincipalErrorHandler responds with 403 Forbidden if the principal was
+// not found, and 500 Internal Server Error otherwise.
+func DefaultPrincipalErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrPrincipalNotFound) {
+		http.Error(w, "user not provisioned", http.StatusForbidden)
+		return
+	}
+
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// resolvePrincipal associates the principal of the session with the request,
+// resolving it if not cached for the ticket. Failures are not cached.
+func (c *Client) resolvePrincipal(r *http.Request, ticket string) error {
+	if c.principalResolver == nil {
+		return nil
+	}
+
+	p, ok := c.principals.get(ticket)
+	if !ok {
+		var err error
+		p, err = c.principalResolver.ResolvePrincipal(r.Context(), getAuthenticationResponse(r))
+		if err != nil {
+			return err
+		}
+
+		c.principals.set(ticket, p)
+	}
+
+	setPrincipal(r, p)
+	return nil
+}
+
+// invalidatePrincipal removes the cached principal of the ticket
+func (c *Client) invalidatePrincipal(ticket string) {
+	c.principals.delete(ticket)
+}
+
+// setPrincipal associates the principal with a http.Request.
+func setPrincipal(r *http.Request, p interface{}) {
+	ctx := context.WithValue(r.Context(), principalKey, p)
+	r2 := r.WithContext(ctx)
+	*r = *r2
+}
+
+// Principal returns the application principal of the authenticated user, as
+// resolved by the PrincipalResolver of the Client. Returns nil if the request
+// is not authenticated or no resolver is configured.
+func Principal(r *http.Request) interface{} {
+	return r.Context().Value(principalKey)
+}
@@ -0,0 +1,153 @@
+package cas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type testUser struct {
+	ID   int
+	Name string
+}
+
+func newPrincipalTestClient(t *testing.T, resolver PrincipalResolver, handler PrincipalErrorHandler) (*Client, *http.Cookie) {
+	casURL, _ := url.Parse("https://cas.example.com/cas/")
+	client := NewClient(&Options{
+		URL:                   casURL,
+		PrincipalResolver:     resolver,
+		PrincipalErrorHandler: handler,
+	})
+
+	if err := client.tickets.Write("ST-1", &AuthenticationResponse{User: "alice"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := client.sessions.Set("session-1", "ST-1"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	return client, &http.Cookie{Name: sessionCookieName, Value: "session-1"}
+}
+
+func TestPrincipalResolver(t *testing.T) {
+	var calls int
+	resolver := PrincipalResolverFunc(func(ctx context.Context, a *AuthenticationResponse) (interface{}, error) {
+		calls++
+		return &testUser{ID: 42, Name: a.User}, nil
+	})
+
+	client, cookie := newPrincipalTestClient(t, resolver, nil)
+
+	var principal *testUser
+	handler := client.HandleFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = Principal(r).(*testUser)
+	})
+
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest("GET", "https://service.example.com/", nil)
+		r.AddCookie(cookie)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		if principal == nil || principal.ID != 42 || principal.Name != "alice" {
+			t.Fatalf("Expected principal for alice, got %#v", principal)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected principal to be resolved once per session, got %d calls", calls)
+	}
+
+	client.invalidatePrincipal("ST-1")
+
+	r := httptest.NewRequest("GET", "https://service.example.com/", nil)
+	r.AddCookie(cookie)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if calls != 2 {
+		t.Errorf("Expected invalidated principal to be resolved again, got %d calls", calls)
+	}
+}
+
+func TestPrincipalResolverErrors(t *testing.T) {
+	tests := map[error]int{
+		ErrPrincipalNotFound:               http.StatusForbidden,
+		errors.New("database unavailable"): http.StatusInternalServerError,
+	}
+
+	for resolveErr, code := range tests {
+		resolver := PrincipalResolverFunc(func(ctx context.Context, a *AuthenticationResponse) (interface{}, error) {
+			return nil, resolveErr
+		})
+
+		client, cookie := newPrincipalTestClient(t, resolver, nil)
+		handler := client.HandleFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("Expected handler not to be called when resolution fails")
+		})
+
+		r := httptest.NewRequest("GET", "https://service.example.com/", nil)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != code {
+			t.Errorf("%v: expected status %d, got %d", resolveErr, code, w.Code)
+		}
+	}
+}
+
+func TestPrincipalErrorHandler(t *testing.T) {
+	resolver := PrincipalResolverFunc(func(ctx context.Context, a *AuthenticationResponse) (interface{}, error) {
+		return nil, ErrPrincipalNotFound
+	})
+
+	client, cookie := newPrincipalTestClient(t, resolver, func(w http.ResponseWriter, r *http.Request, err error) {
+		http.Redirect(w, r, "/register", http.StatusFound)
+	})
+
+	r := httptest.NewRequest("GET", "https://service.example.com/", nil)
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	client.Handle(http.NotFoundHandler()).ServeHTTP(w, r)
+
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/register" {
+		t.Errorf("Expected redirect to /register, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestPrincipalCacheIsBounded(t *testing.T) {
+	resolver := PrincipalResolverFunc(func(ctx context.Context, a *AuthenticationResponse) (interface{}, error) {
+		return &testUser{Name: a.User}, nil
+	})
+
+	casURL, _ := url.Parse("https://cas.example.com/cas/")
+	client := NewClient(&Options{
+		URL:                casURL,
+		PrincipalResolver:  resolver,
+		PrincipalCacheSize: 2,
+	})
+
+	handler := client.HandleFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, user := range []string{"alice", "bob", "carol"} {
+		ticket := "ST-" + user
+		if err := client.tickets.Write(ticket, &AuthenticationResponse{User: user}); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+
+		if err := client.sessions.Set("session-"+user, ticket); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+
+		r := httptest.NewRequest("GET", "https://service.example.com/", nil)
+		r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "session-" + user})
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	if n := len(client.principals.entries); n != 2 {
+		t.Errorf("Expected 2 cached principals, got %v", n)
+	}
+}
@@ -0,0 +1,81 @@
+package cas
+
+import (
+	"net/http"
+)
+
+// DefaultUserAgent is the User-Agent header sent with requests to the cas server
+const DefaultUserAgent = "Golang CAS client gopkg.in/cas"
+
+// RequestMutator modifies a request to the cas server before it is sent.
+// Returning an error aborts the request.
+type RequestMutator func(r *http.Request) error
+
+// ResponseInspector observes a response from the cas server before it is
+// processed, e.g. for auditing. The response body must not be consumed.
+type ResponseInspector func(resp *http.Response)
+
+// SetHeader returns a RequestMutator setting the header to value
+func SetHeader(name, value string) RequestMutator {
+	return func(r *http.Request) error {
+		r.Header.Set(name, value)
+		return nil
+	}
+}
+
+// SetUserAgent returns a RequestMutator replacing DefaultUserAgent with userAgent
+func SetUserAgent(userAgent string) RequestMutator {
+	return SetHeader("User-Agent", userAgent)
+}
+
+// AddQueryParam returns a RequestMutator adding the query parameter to the request url
+func AddQueryParam(name, value string) RequestMutator {
+	return func(r *http.Request) error {
+		q := r.URL.Query()
+		q.Add(name, value)
+		r.URL.RawQuery = q.Encode()
+		return nil
+	}
+}
+
+// ChainRequestMutators returns a RequestMutator applying each mutator in
+// order, stopping at the first error.
+func ChainRequestMutators(mutators ...RequestMutator) RequestMutator {
+	return func(r *http.Request) error {
+		for _, m := range mutators {
+			if m == nil {
+				continue
+			}
+
+			if err := m(r); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// sendRequest sets the default User-Agent, applies the mutator, sends the
+// request and passes the response to the inspector. Failures to send the
+// request are reported as TransportError for op.
+func sendRequest(client *http.Client, op string, r *http.Request, mutate RequestMutator, inspect ResponseInspector) (*http.Response, error) {
+	r.Header.Set("User-Agent", DefaultUserAgent)
+
+	if mutate != nil {
+		if err := mutate(r); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := client.Do(r)
+	if err != nil {
+		return nil, newTransportError(r.Context(), op, r.URL.String(), err)
+	}
+
+	if inspect != nil {
+		inspect(resp)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,100 @@
+package cas
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRequestMutator(t *testing.T) {
+	var seen *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r
+		w.Write([]byte(invalidTicketResponse))
+	}))
+	defer server.Close()
+
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	serviceURL, _ := url.Parse("https://service.example.com/")
+
+	var inspected int
+	validator := NewServiceTicketValidator(server.Client(), casURL)
+	validator.RequestMutator = ChainRequestMutators(
+		SetHeader("X-Gateway-Key", "secret"),
+		AddQueryParam("renew", "true"),
+		SetUserAgent("my-app/1.0"),
+	)
+	validator.ResponseInspector = func(resp *http.Response) {
+		inspected = resp.StatusCode
+	}
+
+	validator.ValidateTicket(serviceURL, "ST-1")
+
+	if seen == nil {
+		t.Fatal("Expected request to reach the cas server")
+	}
+
+	if got := seen.Header.Get("X-Gateway-Key"); got != "secret" {
+		t.Errorf("Expected X-Gateway-Key header, got %q", got)
+	}
+
+	if got := seen.UserAgent(); got != "my-app/1.0" {
+		t.Errorf("Expected custom User-Agent, got %q", got)
+	}
+
+	q := seen.URL.Query()
+	if q.Get("renew") != "true" || q.Get("ticket") != "ST-1" {
+		t.Errorf("Expected renew and ticket query parameters, got %v", q)
+	}
+
+	if inspected != http.StatusOK {
+		t.Errorf("Expected inspector to observe status 200, got %d", inspected)
+	}
+}
+
+func TestRequestMutatorError(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	mutatorErr := errors.New("no credentials")
+
+	restClient := NewRestClient(&RestOptions{
+		CasURL: casURL,
+		Client: server.Client(),
+		RequestMutator: func(r *http.Request) error {
+			return mutatorErr
+		},
+	})
+
+	if _, err := restClient.RequestGrantingTicket("tricia", "hitchhiker"); err != mutatorErr {
+		t.Errorf("Expected mutator error, got %v", err)
+	}
+
+	if requests != 0 {
+		t.Errorf("Expected no requests to be sent, got %d", requests)
+	}
+}
+
+func TestRestClientDefaultUserAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.UserAgent() != DefaultUserAgent {
+			t.Errorf("Expected default User-Agent, got %q", r.UserAgent())
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	restClient := NewRestClient(&RestOptions{CasURL: casURL, Client: server.Client()})
+
+	if err := restClient.Logout("TGT-abc"); err != nil {
+		t.Errorf("Expected logout to succeed, got %v", err)
+	}
+}
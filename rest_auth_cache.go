@@ -0,0 +1,124 @@
+package cas
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultNegativeCacheTTL is the time rejected REST credentials are cached for
+// when no negative ttl is given.
+const DefaultNegativeCacheTTL = 5 * time.Second
+
+// RestCacheOptions configures the cache of REST basic auth results
+type RestCacheOptions struct {
+	TTL         time.Duration // Time successful authentications are cached for, DefaultCacheTTL if zero
+	NegativeTTL time.Duration // Time rejected credentials are cached for, DefaultNegativeCacheTTL if zero, disabled if negative
+	MaxEntries  int           // Maximum number of cached entries, unlimited if zero
+	Clock       Clock         // Custom Clock, if nil the Clock of the RestOptions is used
+	Rand        io.Reader     // Custom source of randomness for the salt of cached credentials, if nil the Rand of the RestOptions is used
+}
+
+// restAuthCache caches the result of authenticating a username and password
+// against the cas server. Credentials are stored as a salted hash, so the
+// cache never holds passwords.
+type restAuthCache struct {
+	cache       *localCache
+	negativeTTL time.Duration
+	salt        []byte
+}
+
+// restAuthEntry is the cached result for a username and password
+type restAuthEntry struct {
+	username string
+	success  *AuthenticationResponse
+	err      error
+}
+
+func newRestAuthCache(options *RestCacheOptions) (*restAuthCache, error) {
+	ttl := options.TTL
+	if ttl == 0 {
+		ttl = DefaultCacheTTL
+	}
+
+	negativeTTL := options.NegativeTTL
+	if negativeTTL == 0 {
+		negativeTTL = DefaultNegativeCacheTTL
+	}
+
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(randOrDefault(options.Rand), salt); err != nil {
+		return nil, fmt.Errorf("cas: rest auth cache: unable to generate salt: %w", err)
+	}
+
+	return &restAuthCache{
+		cache:       newLocalCache(options.Clock, ttl, options.MaxEntries),
+		negativeTTL: negativeTTL,
+		salt:        salt,
+	}, nil
+}
+
+// key returns the salted hash of the username and password
+func (c *restAuthCache) key(username, password string) string {
+	mac := hmac.New(sha256.New, c.salt)
+	fmt.Fprintf(mac, "%d:%s:%s", len(username), username, password)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// get returns the cached result for the username and password
+func (c *restAuthCache) get(username, password string) (restAuthEntry, bool) {
+	v, ok := c.cache.get(c.key(username, password))
+	if !ok {
+		return restAuthEntry{}, false
+	}
+
+	return v.(restAuthEntry), true
+}
+
+// set caches the result of authenticating the username and password. Only
+// rejections by the cas server are cached as negative results.
+func (c *restAuthCache) set(username, password string, success *AuthenticationResponse, err error) {
+	e := restAuthEntry{username: username, success: success, err: err}
+
+	switch {
+	case err == nil:
+		c.cache.set(c.key(username, password), e)
+	case errors.Is(err, ErrInvalidCredentials), errors.Is(err, ErrTicketRejected):
+		c.cache.setTTL(c.key(username, password), e, c.negativeTTL)
+	}
+}
+
+// InvalidateCredentials removes the cached result for the username and password
+func (c *RestClient) InvalidateCredentials(username, password string) {
+	if c.authCache == nil {
+		return
+	}
+
+	c.authCache.cache.delete(c.authCache.key(username, password))
+}
+
+// InvalidateUser removes all cached results for the username, matching both
+// the username sent by the client and the user authenticated by the cas server.
+func (c *RestClient) InvalidateUser(username string) {
+	if c.authCache == nil {
+		return
+	}
+
+	c.authCache.cache.deleteFunc(func(_ string, v interface{}) bool {
+		e := v.(restAuthEntry)
+		return e.username == username || (e.success != nil && e.success.User == username)
+	})
+}
+
+// PurgeAuthCache removes all cached results
+func (c *RestClient) PurgeAuthCache() {
+	if c.authCache == nil {
+		return
+	}
+
+	c.authCache.cache.purge()
+}
@@ -0,0 +1,173 @@
+package cas
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newCountingRestServer(grants *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cas/v1/tickets":
+			atomic.AddInt32(grants, 1)
+			if r.FormValue("password") != "hitchhiker" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			w.Header().Set("Location", "/cas/v1/tickets/TGT-abc")
+			w.WriteHeader(http.StatusCreated)
+		case "/cas/v1/tickets/TGT-abc":
+			w.Write([]byte("ST-1"))
+		case "/cas/serviceValidate":
+			w.Write([]byte(`<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+    <cas:user>` + r.URL.Query().Get("ticket") + `</cas:user>
+  </cas:authenticationSuccess>
+</cas:serviceResponse>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestRestHandlerAuthCache(t *testing.T) {
+	var grants int32
+	server := newCountingRestServer(&grants)
+	defer server.Close()
+
+	clock := NewFakeClock(time.Now())
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	serviceURL, _ := url.Parse("https://service.example.com/")
+	restClient := NewRestClient(&RestOptions{
+		CasURL:     casURL,
+		ServiceURL: serviceURL,
+		Client:     server.Client(),
+		AuthCache:  &RestCacheOptions{TTL: time.Minute, NegativeTTL: time.Second, Clock: clock},
+	})
+
+	handler := restClient.HandleFunc(func(w http.ResponseWriter, r *http.Request) {})
+	serve := func(username, password string) int {
+		r := httptest.NewRequest("GET", "/api", nil)
+		r.SetBasicAuth(username, password)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := serve("tricia", "hitchhiker"); code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", code)
+		}
+	}
+
+	if n := atomic.LoadInt32(&grants); n != 1 {
+		t.Errorf("Expected 1 granting ticket request, got %d", n)
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := serve("arthur", "dent"); code != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401, got %d", code)
+		}
+	}
+
+	if n := atomic.LoadInt32(&grants); n != 2 {
+		t.Errorf("Expected rejected credentials to be cached, got %d requests", n)
+	}
+
+	clock.Advance(2 * time.Second)
+	serve("arthur", "dent")
+
+	if n := atomic.LoadInt32(&grants); n != 3 {
+		t.Errorf("Expected negative entry to expire, got %d requests", n)
+	}
+
+	restClient.InvalidateUser("tricia")
+	serve("tricia", "hitchhiker")
+
+	if n := atomic.LoadInt32(&grants); n != 4 {
+		t.Errorf("Expected invalidated user to be authenticated again, got %d requests", n)
+	}
+
+	restClient.InvalidateCredentials("tricia", "hitchhiker")
+	serve("tricia", "hitchhiker")
+
+	restClient.PurgeAuthCache()
+	serve("tricia", "hitchhiker")
+
+	if n := atomic.LoadInt32(&grants); n != 6 {
+		t.Errorf("Expected invalidated credentials to be authenticated again, got %d requests", n)
+	}
+}
+
+func TestRestAuthCacheKeyIsSalted(t *testing.T) {
+	a, err := newRestAuthCache(&RestCacheOptions{})
+	if err != nil {
+		t.Fatalf("newRestAuthCache failed: %v", err)
+	}
+
+	b, err := newRestAuthCache(&RestCacheOptions{})
+	if err != nil {
+		t.Fatalf("newRestAuthCache failed: %v", err)
+	}
+
+	if a.key("tricia", "hitchhiker") == b.key("tricia", "hitchhiker") {
+		t.Errorf("Expected keys of differently salted caches to differ")
+	}
+}
+
+func TestRestAuthCacheDisabledWithoutRandomness(t *testing.T) {
+	casURL, _ := url.Parse("https://cas.example.com/cas/")
+	restClient := NewRestClient(&RestOptions{
+		CasURL:    casURL,
+		AuthCache: &RestCacheOptions{Rand: strings.NewReader("")},
+	})
+
+	if restClient.authCache != nil {
+		t.Errorf("Expected auth cache to be disabled when no salt can be generated")
+	}
+}
+
+func TestRestAuthCacheInvalidation(t *testing.T) {
+	var grants int32
+	server := newCountingRestServer(&grants)
+	defer server.Close()
+
+	casURL, _ := url.Parse(server.URL + "/cas/")
+	serviceURL, _ := url.Parse("https://service.example.com/")
+	restClient := NewRestClient(&RestOptions{
+		CasURL:     casURL,
+		ServiceURL: serviceURL,
+		Client:     server.Client(),
+		AuthCache:  &RestCacheOptions{TTL: time.Minute},
+	})
+
+	handler := restClient.HandleFunc(func(w http.ResponseWriter, r *http.Request) {})
+	serve := func() {
+		r := httptest.NewRequest("GET", "/api", nil)
+		r.Header.Set("Authorization", "basic "+base64.StdEncoding.EncodeToString([]byte("tricia:hitchhiker")))
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	serve()
+	restClient.InvalidateCredentials("tricia", "hitchhiker")
+	serve()
+
+	if n := atomic.LoadInt32(&grants); n != 2 {
+		t.Errorf("Expected credentials sent with a lower case scheme to be invalidated, got %d requests", n)
+	}
+
+	// the cas server authenticates tricia as ST-1
+	restClient.InvalidateUser("ST-1")
+	serve()
+
+	if n := atomic.LoadInt32(&grants); n != 3 {
+		t.Errorf("Expected InvalidateUser to match the cas user, got %d requests", n)
+	}
+}
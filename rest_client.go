@@ -1,11 +1,14 @@
 package cas
 
 import (
-	"fmt"
+	"context"
+	"crypto"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
 
 	"github.com/golang/glog"
 )
@@ -24,6 +27,27 @@ type RestOptions struct {
 	ServiceURL *url.URL
 	Client     *http.Client
 	URLScheme  URLScheme
+	Clock      Clock     // Custom Clock, if nil the system time is used
+	Rand       io.Reader // Custom source of randomness for retry jitter, if nil crypto/rand is used
+
+	Retry          *RetryPolicy    // Retry policy for ticket validation, if nil validation is not retried
+	CircuitBreaker *CircuitBreaker // Circuit breaker for ticket validation, if nil none is used
+
+	// ValidationURLs lists the cas servers used for ticket validation, if empty CasURL is used.
+	// Requests for granting and service tickets always go to CasURL.
+	ValidationURLs     []*url.URL
+	ValidationStrategy EndpointStrategy // How validation requests are spread over ValidationURLs
+
+	MaxResponseSize       int64 // Largest validation response accepted, DefaultMaxResponseSize if zero
+	StrictResponseParsing bool  // Reject validation responses with unknown structure
+
+	RequestMutator    RequestMutator    // Optional hook modifying requests to the cas server
+	ResponseInspector ResponseInspector // Optional hook observing responses from the cas server
+
+	AttributeNormalizer *AttributeNormalizer // Optional normalisation of attribute names after validation
+	PrivateKey          crypto.Decrypter     // Private key of the service, decrypts the credential and proxy granting ticket attributes
+
+	AuthCache *RestCacheOptions // Cache basic auth results of the rest handler, disabled if nil
 }
 
 // RestClient uses the rest protocol provided by cas
@@ -32,6 +56,10 @@ type RestClient struct {
 	serviceURL  *url.URL
 	client      *http.Client
 	stValidator *ServiceTicketValidator
+	privateKey  crypto.Decrypter
+	mutate      RequestMutator
+	inspect     ResponseInspector
+	authCache   *restAuthCache
 }
 
 // NewRestClient creates a new client for the cas rest protocol with the provided options
@@ -54,11 +82,46 @@ func NewRestClient(options *RestOptions) *RestClient {
 		urlScheme = NewDefaultURLScheme(options.CasURL)
 	}
 
+	clock := clockOrDefault(options.Clock)
+
+	stValidator := NewServiceTicketValidator(client, options.CasURL)
+	stValidator.endpoints = validationEndpoints(options.ValidationStrategy, clock, urlScheme, options.ValidationURLs)
+	stValidator.clock = clock
+	stValidator.rand = randOrDefault(options.Rand)
+	stValidator.Retry = options.Retry
+	stValidator.CircuitBreaker = options.CircuitBreaker
+	stValidator.MaxResponseSize = options.MaxResponseSize
+	stValidator.Strict = options.StrictResponseParsing
+	stValidator.RequestMutator = options.RequestMutator
+	stValidator.ResponseInspector = options.ResponseInspector
+	stValidator.AttributeNormalizer = options.AttributeNormalizer
+
+	var authCache *restAuthCache
+	if options.AuthCache != nil {
+		cacheOptions := *options.AuthCache
+		if cacheOptions.Clock == nil {
+			cacheOptions.Clock = clock
+		}
+
+		if cacheOptions.Rand == nil {
+			cacheOptions.Rand = options.Rand
+		}
+
+		var err error
+		if authCache, err = newRestAuthCache(&cacheOptions); err != nil {
+			glog.Errorf("%v, auth cache disabled", err)
+		}
+	}
+
 	return &RestClient{
 		urlScheme:   urlScheme,
 		serviceURL:  options.ServiceURL,
 		client:      client,
-		stValidator: NewServiceTicketValidator(client, options.CasURL),
+		stValidator: stValidator,
+		privateKey:  options.PrivateKey,
+		mutate:      options.RequestMutator,
+		inspect:     options.ResponseInspector,
+		authCache:   authCache,
 	}
 }
 
@@ -77,6 +140,11 @@ func (c *RestClient) HandleFunc(h func(http.ResponseWriter, *http.Request)) http
 
 // RequestGrantingTicket returns a new TGT, if the username and password authentication was successful
 func (c *RestClient) RequestGrantingTicket(username string, password string) (TicketGrantingTicket, error) {
+	return c.RequestGrantingTicketContext(context.Background(), username, password)
+}
+
+// RequestGrantingTicketContext is RequestGrantingTicket with a context controlling the lifetime of the request
+func (c *RestClient) RequestGrantingTicketContext(ctx context.Context, username string, password string) (TicketGrantingTicket, error) {
 	// request:
 	// POST /cas/v1/tickets HTTP/1.0
 	// username=battags&password=password&additionalParam1=paramvalue
@@ -90,29 +158,42 @@ func (c *RestClient) RequestGrantingTicket(username string, password string) (Ti
 	values.Set("username", username)
 	values.Set("password", password)
 
-	resp, err := c.client.PostForm(endpoint.String(), values)
+	resp, err := c.postForm(ctx, "request granting ticket", endpoint, values)
 	if err != nil {
 		return "", err
 	}
 
+	resp.Body.Close()
+
 	// response:
 	// 201 Created
 	// Location: http://www.whatever.com/cas/v1/tickets/{TGT id}
 
-	if resp.StatusCode != 201 {
-		return "", fmt.Errorf("ticket endoint returned status code %v", resp.StatusCode)
+	switch resp.StatusCode {
+	case http.StatusCreated:
+	case http.StatusBadRequest, http.StatusUnauthorized:
+		return "", &CredentialError{Username: username, StatusCode: resp.StatusCode}
+	default:
+		return "", &StatusError{Op: "request granting ticket", URL: endpoint.String(), StatusCode: resp.StatusCode}
 	}
 
-	tgt := path.Base(resp.Header.Get("Location"))
-	if tgt == "" {
-		return "", fmt.Errorf("does not return a valid location header")
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", &MalformedResponseError{Response: "granting ticket response", Err: errMissingLocation}
 	}
 
+	tgt := path.Base(location)
+
 	return TicketGrantingTicket(tgt), nil
 }
 
 // RequestServiceTicket requests a service ticket with the TGT for the configured service url
 func (c *RestClient) RequestServiceTicket(tgt TicketGrantingTicket) (ServiceTicket, error) {
+	return c.RequestServiceTicketContext(context.Background(), tgt)
+}
+
+// RequestServiceTicketContext is RequestServiceTicket with a context controlling the lifetime of the request
+func (c *RestClient) RequestServiceTicketContext(ctx context.Context, tgt TicketGrantingTicket) (ServiceTicket, error) {
 	// request:
 	// POST /cas/v1/tickets/{TGT id} HTTP/1.0
 	// service={form encoded parameter for the service url}
@@ -124,24 +205,24 @@ func (c *RestClient) RequestServiceTicket(tgt TicketGrantingTicket) (ServiceTick
 	values := url.Values{}
 	values.Set("service", c.serviceURL.String())
 
-	resp, err := c.client.PostForm(endpoint.String(), values)
+	resp, err := c.postForm(ctx, "request service ticket", endpoint, values)
 	if err != nil {
 		return "", err
 	}
 
+	defer resp.Body.Close()
+
 	// response:
 	// 200 OK
 	// ST-1-FFDFHDSJKHSDFJKSDHFJKRUEYREWUIFSD2132
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("service ticket endoint returned status code %v", resp.StatusCode)
+		return "", &StatusError{Op: "request service ticket", URL: endpoint.String(), StatusCode: resp.StatusCode}
 	}
 
-	defer resp.Body.Close()
-
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return "", newTransportError(ctx, "request service ticket", endpoint.String(), err)
 	}
 
 	return ServiceTicket(data), nil
@@ -149,30 +230,54 @@ func (c *RestClient) RequestServiceTicket(tgt TicketGrantingTicket) (ServiceTick
 
 // ValidateServiceTicket validates the service ticket and returns an AuthenticationResponse
 func (c *RestClient) ValidateServiceTicket(st ServiceTicket) (*AuthenticationResponse, error) {
-	return c.stValidator.ValidateTicket(c.serviceURL, string(st))
+	return c.ValidateServiceTicketContext(context.Background(), st)
+}
+
+// ValidateServiceTicketContext is ValidateServiceTicket with a context controlling the lifetime of the requests
+func (c *RestClient) ValidateServiceTicketContext(ctx context.Context, st ServiceTicket) (*AuthenticationResponse, error) {
+	return c.stValidator.ValidateTicketContext(ctx, c.serviceURL, string(st))
 }
 
 // Logout destroys the given granting ticket
 func (c *RestClient) Logout(tgt TicketGrantingTicket) error {
+	return c.LogoutContext(context.Background(), tgt)
+}
+
+// LogoutContext is Logout with a context controlling the lifetime of the request
+func (c *RestClient) LogoutContext(ctx context.Context, tgt TicketGrantingTicket) error {
 	// DELETE /cas/v1/tickets/TGT-fdsjfsdfjkalfewrihfdhfaie HTTP/1.0
 	endpoint, err := c.urlScheme.RestLogout(string(tgt))
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("DELETE", endpoint.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", endpoint.String(), nil)
 	if err != nil {
 		return err
 	}
 
-	resp, err := c.client.Do(req)
+	resp, err := sendRequest(c.client, "logout", req, c.mutate, c.inspect)
 	if err != nil {
 		return err
 	}
 
+	resp.Body.Close()
+
 	if resp.StatusCode != 200 && resp.StatusCode != 204 {
-		return fmt.Errorf("could not destroy granting ticket %v, server returned %v", tgt, resp.StatusCode)
+		return &StatusError{Op: "logout", URL: endpoint.String(), StatusCode: resp.StatusCode}
 	}
 
 	return nil
 }
+
+// postForm issues a POST to the endpoint with the url encoded values as body
+func (c *RestClient) postForm(ctx context.Context, op string, endpoint *url.URL, values url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint.String(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return sendRequest(c.client, op, req, c.mutate, c.inspect)
+}
@@ -1,6 +1,9 @@
 package cas
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -186,3 +189,63 @@ func TestLogout(t *testing.T) {
 		t.Errorf("logout should failed for this TGT")
 	}
 }
+
+func TestRequestGrantingTicketContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/cas/v1/tickets/TGT-abc")
+		w.WriteHeader(201)
+	}))
+	defer server.Close()
+
+	casURL, err := url.Parse(server.URL + "/cas/")
+	if err != nil {
+		t.Error("failed to create cas url from test server")
+	}
+
+	restClient := NewRestClient(&RestOptions{
+		CasURL: casURL,
+		Client: server.Client(),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = restClient.RequestGrantingTicketContext(ctx, "tricia", "hitchhiker")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled but received %v", err)
+	}
+
+	err = restClient.LogoutContext(ctx, TicketGrantingTicket("TGT-abc"))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled but received %v", err)
+	}
+}
+
+func TestRestClientUsesClockAndRand(t *testing.T) {
+	clock := newTestClock()
+	entropy := bytes.NewReader(make([]byte, 64))
+
+	casURL, _ := url.Parse("https://cas.example.com/cas/")
+	restClient := NewRestClient(&RestOptions{
+		CasURL:    casURL,
+		Clock:     clock,
+		Rand:      entropy,
+		AuthCache: &RestCacheOptions{},
+	})
+
+	if restClient.stValidator.clock != clock || restClient.stValidator.endpoints.clock != clock {
+		t.Errorf("Expected ticket validation to use the clock of the options")
+	}
+
+	if restClient.stValidator.rand != entropy {
+		t.Errorf("Expected ticket validation to use the randomness of the options")
+	}
+
+	if restClient.authCache == nil || restClient.authCache.cache.clock != clock {
+		t.Errorf("Expected auth cache to use the clock of the options")
+	}
+
+	if entropy.Len() != 32 {
+		t.Errorf("Expected auth cache salt to be read from the randomness of the options")
+	}
+}
@@ -1,6 +1,7 @@
 package cas
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/golang/glog"
@@ -26,10 +27,7 @@ func (ch *restClientHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO we should implement a short cache to avoid hitting cas server on every request
-	// the cache could use the authorization header as key and the authenticationResponse as value
-
-	success, err := ch.authenticate(username, password)
+	success, err := ch.cachedAuthenticate(r.Context(), username, password)
 	if err != nil {
 		if glog.V(1) {
 			glog.Infof("cas: rest authentication failed %v", err)
@@ -40,20 +38,42 @@ func (ch *restClientHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	setAuthenticationResponse(r, success)
+	setPrivateKey(r, ch.c.privateKey)
 	ch.h.ServeHTTP(w, r)
 	return
 }
 
-func (ch *restClientHandler) authenticate(username string, password string) (*AuthenticationResponse, error) {
-	tgt, err := ch.c.RequestGrantingTicket(username, password)
+// cachedAuthenticate answers from the auth cache of the RestClient, if
+// enabled, before authenticating against the cas server.
+func (ch *restClientHandler) cachedAuthenticate(ctx context.Context, username, password string) (*AuthenticationResponse, error) {
+	cache := ch.c.authCache
+	if cache == nil {
+		return ch.authenticate(ctx, username, password)
+	}
+
+	if e, ok := cache.get(username, password); ok {
+		if glog.V(2) {
+			glog.Infof("cas: rest authentication for %v answered from cache", username)
+		}
+
+		return e.success, e.err
+	}
+
+	success, err := ch.authenticate(ctx, username, password)
+	cache.set(username, password, success, err)
+	return success, err
+}
+
+func (ch *restClientHandler) authenticate(ctx context.Context, username string, password string) (*AuthenticationResponse, error) {
+	tgt, err := ch.c.RequestGrantingTicketContext(ctx, username, password)
 	if err != nil {
 		return nil, err
 	}
 
-	st, err := ch.c.RequestServiceTicket(tgt)
+	st, err := ch.c.RequestServiceTicketContext(ctx, tgt)
 	if err != nil {
 		return nil, err
 	}
 
-	return ch.c.ValidateServiceTicket(st)
+	return ch.c.ValidateServiceTicketContext(ctx, st)
 }
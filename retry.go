@@ -0,0 +1,65 @@
+package cas
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// Retry policy defaults
+const (
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff     = 2 * time.Second
+)
+
+// RetryPolicy controls how failed ticket validation requests are retried.
+//
+// Only failures which indicate the CAS server was unavailable are retried,
+// a ticket rejected by the server is never retried.
+type RetryPolicy struct {
+	MaxAttempts    int           // Total number of attempts, no retries if less than 2
+	InitialBackoff time.Duration // Upper bound of the first delay, DefaultRetryInitialBackoff if zero
+	MaxBackoff     time.Duration // Upper bound of any delay, DefaultRetryMaxBackoff if zero
+}
+
+// backoff returns the delay before the given retry, counting from 1, jittered
+// with randomness read from entropy. The delay is not jittered if entropy fails.
+func (p *RetryPolicy) backoff(retry int, entropy io.Reader) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = DefaultRetryInitialBackoff
+	}
+
+	max := p.MaxBackoff
+	if max <= 0 {
+		max = DefaultRetryMaxBackoff
+	}
+
+	d := initial
+	for i := 1; i < retry && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max {
+		d = max
+	}
+
+	// full jitter
+	var b [8]byte
+	if _, err := io.ReadFull(entropy, b[:]); err != nil {
+		return d
+	}
+
+	return time.Duration(binary.BigEndian.Uint64(b[:]) % uint64(d+1))
+}
+
+// sleep waits for d on the clock or until ctx is done.
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	select {
+	case <-clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,227 @@
+package cas
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// RoleRule grants an application role when a memberOf entry or attribute value
+// matches. Exactly one of Exact, Regex and DN must be set.
+type RoleRule struct {
+	Role      string            `yaml:"role"`      // Role granted, with Regex may reference capture groups as $1 or ${name}
+	Attribute string            `yaml:"attribute"` // Attribute whose values are matched, memberOf if empty
+	Exact     string            `yaml:"exact"`     // Value must be equal
+	Regex     string            `yaml:"regex"`     // Value must match the regular expression
+	DN        map[string]string `yaml:"dn"`        // Value must be a DN containing each attribute type and value, e.g. cn: ops
+}
+
+// RoleMapper maps the groups and attributes of an authenticated user to
+// application roles.
+//
+// A RoleMapper is usually loaded from YAML:
+//
+//	rules:
+//	  - role: admin
+//	    exact: cn=ops,ou=groups,dc=example,dc=com
+//	  - role: viewer
+//	    dn: {cn: support, ou: groups}
+//	  - role: team-$1
+//	    attribute: department
+//	    regex: ^(\w+)$
+type RoleMapper struct {
+	rules []roleRule
+}
+
+// roleRule is a validated RoleRule
+type roleRule struct {
+	RoleRule
+	re *regexp.Regexp
+}
+
+// NewRoleMapper creates a RoleMapper from the rules
+func NewRoleMapper(rules ...RoleRule) (*RoleMapper, error) {
+	m := &RoleMapper{}
+
+	for i, rule := range rules {
+		if rule.Role == "" {
+			return nil, fmt.Errorf("cas: role mapper: rule %d: missing role", i)
+		}
+
+		matchers := 0
+		for _, set := range []bool{rule.Exact != "", rule.Regex != "", len(rule.DN) > 0} {
+			if set {
+				matchers++
+			}
+		}
+
+		if matchers != 1 {
+			return nil, fmt.Errorf("cas: role mapper: rule %d: exactly one of exact, regex and dn is required", i)
+		}
+
+		r := roleRule{RoleRule: rule}
+		if rule.Regex != "" {
+			re, err := regexp.Compile(rule.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("cas: role mapper: rule %d: %v", i, err)
+			}
+
+			r.re = re
+		}
+
+		m.rules = append(m.rules, r)
+	}
+
+	return m, nil
+}
+
+// LoadRoleMapper creates a RoleMapper from its YAML configuration
+func LoadRoleMapper(data []byte) (*RoleMapper, error) {
+	var config struct {
+		Rules []RoleRule `yaml:"rules"`
+	}
+
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("cas: role mapper: %v", err)
+	}
+
+	return NewRoleMapper(config.Rules...)
+}
+
+// Roles returns the roles granted to the authenticated user, in rule order
+// without duplicates.
+func (m *RoleMapper) Roles(a *AuthenticationResponse) []string {
+	if m == nil || a == nil {
+		return nil
+	}
+
+	var roles []string
+	seen := make(map[string]bool)
+
+	for _, rule := range m.rules {
+		values := a.MemberOf
+		if rule.Attribute != "" {
+			values = a.Attributes[rule.Attribute]
+		}
+
+		for _, v := range values {
+			role, ok := rule.match(v)
+			if !ok || role == "" || seen[role] {
+				continue
+			}
+
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+
+	return roles
+}
+
+// match returns the role granted for value, if any
+func (r *roleRule) match(value string) (string, bool) {
+	switch {
+	case r.re != nil:
+		m := r.re.FindStringSubmatchIndex(value)
+		if m == nil {
+			return "", false
+		}
+
+		return string(r.re.ExpandString(nil, r.Role, value, m)), true
+
+	case len(r.DN) > 0:
+		return r.Role, dnContains(value, r.DN)
+
+	default:
+		return r.Role, value == r.Exact
+	}
+}
+
+// dnContains reports whether the distinguished name contains a component for
+// each attribute type and value. Comparisons ignore case.
+func dnContains(dn string, want map[string]string) bool {
+	components := parseDN(dn)
+
+	for typ, value := range want {
+		found := false
+		for _, c := range components {
+			if strings.EqualFold(c[0], typ) && strings.EqualFold(c[1], value) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseDN splits a distinguished name into attribute type and value pairs,
+// honouring backslash escapes. Multi-valued RDNs are split into their parts.
+func parseDN(dn string) [][2]string {
+	var components [][2]string
+	var current strings.Builder
+	var typ string
+
+	flush := func() {
+		if typ != "" {
+			components = append(components, [2]string{typ, strings.TrimSpace(current.String())})
+		}
+
+		typ = ""
+		current.Reset()
+	}
+
+	for i := 0; i < len(dn); i++ {
+		switch c := dn[i]; {
+		case c == '\\' && i+1 < len(dn):
+			i++
+			current.WriteByte(dn[i])
+		case c == '=' && typ == "":
+			typ = strings.TrimSpace(current.String())
+			current.Reset()
+		case c == ',' || c == ';' || c == '+':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+
+	flush()
+	return components
+}
+
+// Roles returns the application roles of the authenticated user, as mapped by
+// the RoleMapper of the Client associated with the request.
+func Roles(r *http.Request) []string {
+	c := getClient(r)
+	if c == nil {
+		return nil
+	}
+
+	return c.roleMapper.Roles(getAuthenticationResponse(r))
+}
+
+// HasRole indicates whether the authenticated user has the application role
+func HasRole(r *http.Request, role string) bool {
+	for _, have := range Roles(r) {
+		if have == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequireRole returns Middleware allowing access to users with any of the roles
+func RequireRole(roles ...string) Middleware {
+	return requireRequest(func(r *http.Request, _ *AuthenticationResponse) bool {
+		return containsAny(Roles(r), roles)
+	})
+}
@@ -0,0 +1,102 @@
+package cas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+const testRoleMapperConfig = `
+rules:
+  - role: admin
+    exact: cn=ops,ou=groups,dc=example,dc=com
+  - role: viewer
+    dn: {cn: support, ou: groups}
+  - role: team-$1
+    attribute: department
+    regex: ^(\w+)$
+  - role: ${env}-deployer
+    regex: ^cn=deploy-(?P<env>[a-z]+),
+`
+
+func TestRoleMapper(t *testing.T) {
+	m, err := LoadRoleMapper([]byte(testRoleMapperConfig))
+	if err != nil {
+		t.Fatalf("LoadRoleMapper failed: %v", err)
+	}
+
+	a := &AuthenticationResponse{
+		User: "alice",
+		MemberOf: []string{
+			"cn=ops,ou=groups,dc=example,dc=com",
+			"CN=Support, OU=Groups, DC=example, DC=com",
+			"cn=deploy-prod,ou=groups,dc=example,dc=com",
+			"cn=ops,ou=groups,dc=example,dc=com",
+		},
+		Attributes: UserAttributes{"department": {"engineering", "not a word"}},
+	}
+
+	expected := []string{"admin", "viewer", "team-engineering", "prod-deployer"}
+	if roles := m.Roles(a); !reflect.DeepEqual(roles, expected) {
+		t.Errorf("Expected roles %v, got %v", expected, roles)
+	}
+
+	if roles := m.Roles(&AuthenticationResponse{User: "bob"}); len(roles) != 0 {
+		t.Errorf("Expected no roles, got %v", roles)
+	}
+}
+
+func TestRoleMapperInvalidRules(t *testing.T) {
+	tests := map[string][]RoleRule{
+		"missing role":     {{Exact: "x"}},
+		"missing matcher":  {{Role: "admin"}},
+		"several matchers": {{Role: "admin", Exact: "x", Regex: "x"}},
+		"invalid regex":    {{Role: "admin", Regex: "("}},
+	}
+
+	for name, rules := range tests {
+		if _, err := NewRoleMapper(rules...); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if _, err := LoadRoleMapper([]byte("rules:\n  - role: admin\n    exakt: x\n")); err == nil {
+		t.Errorf("Expected unknown field to be rejected")
+	}
+}
+
+func TestParseDN(t *testing.T) {
+	dn := `cn=Smith\, John+uid=jsmith, ou=people,dc=example`
+	expected := [][2]string{{"cn", "Smith, John"}, {"uid", "jsmith"}, {"ou", "people"}, {"dc", "example"}}
+
+	if got := parseDN(dn); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	m, _ := NewRoleMapper(RoleRule{Role: "admin", DN: map[string]string{"cn": "ops"}})
+	casURL, _ := url.Parse("https://cas.example.com/cas/")
+	client := NewClient(&Options{URL: casURL, RoleMapper: m})
+
+	handler := RequireRole("admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !HasRole(r, "admin") || HasRole(r, "viewer") {
+			t.Errorf("Unexpected roles %v", Roles(r))
+		}
+	}))
+
+	for groups, code := range map[string]int{"cn=ops,dc=example": http.StatusOK, "cn=dev,dc=example": http.StatusForbidden} {
+		r := httptest.NewRequest("GET", "https://service.example.com/", nil)
+		setClient(r, client)
+		setAuthenticationResponse(r, &AuthenticationResponse{User: "alice", MemberOf: []string{groups}})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != code {
+			t.Errorf("%s: expected status %d, got %d", groups, code, w.Code)
+		}
+	}
+}
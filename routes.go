@@ -0,0 +1,145 @@
+package cas
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// DefaultLogoutPath is the path which logs the user out of CAS when no logout path is configured
+const DefaultLogoutPath = "/logout"
+
+// gatewayCookieName marks a request which has already been sent to CAS with gateway=true
+const gatewayCookieName = "_cas_gateway"
+
+// AuthMode controls how Client.Handler protects a route
+type AuthMode int
+
+// AuthMode values
+const (
+	// AuthRequired redirects unauthenticated users to the CAS login page.
+	AuthRequired AuthMode = iota
+
+	// AuthOptional sends unauthenticated users to CAS once with gateway=true,
+	// authenticating them only if they already have a single sign-on session.
+	AuthOptional
+
+	// AuthPublic passes requests on without authentication.
+	AuthPublic
+)
+
+// Route sets how requests for matching paths are protected by Client.Handler
+type Route struct {
+	Prefix  string   // Path prefix matched by the route
+	Pattern string   // path.Match pattern matched by the route, used if Prefix is empty
+	Mode    AuthMode // Protection of matching requests
+}
+
+// matches reports whether the route applies to the url path
+func (rt Route) matches(urlPath string) bool {
+	if rt.Prefix != "" {
+		return strings.HasPrefix(urlPath, rt.Prefix)
+	}
+
+	ok, _ := path.Match(rt.Pattern, urlPath)
+	return ok
+}
+
+// authMode returns the mode of the first route matching the url path,
+// AuthRequired if no route matches. The path is cleaned before matching, so
+// dot segments can not escape a public prefix.
+func (c *Client) authMode(urlPath string) AuthMode {
+	urlPath = cleanPath(urlPath)
+	for _, rt := range c.routes {
+		if rt.matches(urlPath) {
+			return rt.Mode
+		}
+	}
+
+	return AuthRequired
+}
+
+// cleanPath returns the shortest rooted path equivalent to p, keeping a
+// trailing slash.
+func cleanPath(p string) string {
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	return cleaned
+}
+
+// samePath reports whether the paths are equivalent once cleaned, ignoring a
+// trailing slash.
+func samePath(a, b string) bool {
+	return path.Clean("/"+a) == path.Clean("/"+b)
+}
+
+// bypassesAuth reports whether requests with the method skip authentication
+func (c *Client) bypassesAuth(method string) bool {
+	for _, m := range c.bypassMethods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redirectToGateway sends the user to CAS with gateway=true, marking the
+// request with a cookie so the redirect is only attempted once.
+func (c *Client) redirectToGateway(w http.ResponseWriter, r *http.Request) {
+	login, err := c.LoginUrlForRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	u, err := url.Parse(login)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	q := u.Query()
+	q.Set("gateway", "true")
+	u.RawQuery = q.Encode()
+
+	if glog.V(2) {
+		glog.Infof("Redirecting client to %v with status %v", u, http.StatusFound)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     gatewayCookieName,
+		Value:    "1",
+		Path:     c.cookie.Path,
+		Domain:   c.cookie.Domain,
+		MaxAge:   300,
+		HttpOnly: true,
+		Secure:   c.cookie.Secure,
+		SameSite: c.cookie.SameSite,
+	})
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// gatewayAttempted reports whether the request was already sent to CAS with gateway=true
+func gatewayAttempted(r *http.Request) bool {
+	_, err := r.Cookie(gatewayCookieName)
+	return err == nil
+}
+
+// logoutLocally ends the session with the service without logging out of
+// CAS, and redirects to the root of the service.
+func (c *Client) logoutLocally(w http.ResponseWriter, r *http.Request) {
+	if glog.V(2) {
+		glog.Infof("Logging out locally, redirecting client to / with status %v", http.StatusFound)
+	}
+
+	c.clearSession(w, r)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
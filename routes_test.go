@@ -0,0 +1,152 @@
+package cas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHandlerRoutes(t *testing.T) {
+	casURL, _ := url.Parse("https://cas.example.com/cas/")
+	client := NewClient(&Options{
+		URL: casURL,
+		Routes: []Route{
+			{Prefix: "/static/", Mode: AuthPublic},
+			{Pattern: "/news/*", Mode: AuthOptional},
+			{Pattern: "/healthz", Mode: AuthPublic},
+			{Prefix: "/", Mode: AuthRequired},
+		},
+		LogoutPath:      "/signout",
+		LocalLogoutPath: "/signout-local",
+		BypassMethods:   []string{"OPTIONS"},
+	})
+
+	handler := client.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method   string
+		path     string
+		cookie   *http.Cookie
+		code     int
+		location string
+	}{
+		{"GET", "/static/app.css", nil, http.StatusOK, ""},
+		{"GET", "/healthz", nil, http.StatusOK, ""},
+		{"GET", "/static/../api/items", nil, http.StatusFound, "https://cas.example.com/cas/login?service="},
+		{"GET", "/static//../api/items", nil, http.StatusFound, "https://cas.example.com/cas/login?service="},
+		{"GET", "/api/../static/app.css", nil, http.StatusOK, ""},
+		{"OPTIONS", "/api/items", nil, http.StatusOK, ""},
+		{"GET", "/api/items", nil, http.StatusFound, "https://cas.example.com/cas/login?service="},
+		{"GET", "/news/today", nil, http.StatusFound, "gateway=true"},
+		{"GET", "/news/today", &http.Cookie{Name: gatewayCookieName, Value: "1"}, http.StatusOK, ""},
+		{"GET", "/signout", nil, http.StatusFound, "https://cas.example.com/cas/logout"},
+		{"GET", "/signout-local", nil, http.StatusFound, "/"},
+		{"GET", "/logout", nil, http.StatusFound, "https://cas.example.com/cas/login"},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest(tc.method, "https://service.example.com"+tc.path, nil)
+		if tc.cookie != nil {
+			r.AddCookie(tc.cookie)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tc.code {
+			t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.path, tc.code, w.Code)
+		}
+
+		if loc := w.Header().Get("Location"); !strings.Contains(loc, tc.location) {
+			t.Errorf("%s %s: expected location containing %q, got %q", tc.method, tc.path, tc.location, loc)
+		}
+	}
+}
+
+func TestHandlerGatewaySetsCookie(t *testing.T) {
+	casURL, _ := url.Parse("https://cas.example.com/cas/")
+	client := NewClient(&Options{
+		URL:    casURL,
+		Routes: []Route{{Prefix: "/", Mode: AuthOptional}},
+	})
+
+	handler := client.Handler(http.NotFoundHandler())
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "https://service.example.com/", nil))
+
+	found := false
+	for _, c := range w.Result().Cookies() {
+		if c.Name == gatewayCookieName {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("Expected gateway redirect to set %s cookie", gatewayCookieName)
+	}
+}
+
+func TestHandlerDefaultLogoutPath(t *testing.T) {
+	casURL, _ := url.Parse("https://cas.example.com/cas/")
+	client := NewClient(&Options{URL: casURL})
+
+	r := httptest.NewRequest("GET", "https://service.example.com/logout", nil)
+	setAuthenticationResponse(r, &AuthenticationResponse{User: "alice"})
+
+	w := httptest.NewRecorder()
+	client.Handler(http.NotFoundHandler()).ServeHTTP(w, r)
+
+	if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "https://cas.example.com/cas/logout") {
+		t.Errorf("Expected redirect to cas logout, got %q", loc)
+	}
+}
+
+func TestHandlerLogoutPathIsCleaned(t *testing.T) {
+	casURL, _ := url.Parse("https://cas.example.com/cas/")
+	client := NewClient(&Options{
+		URL:             casURL,
+		LocalLogoutPath: "/signout-local",
+		BypassMethods:   []string{"OPTIONS"},
+	})
+
+	handler := client.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{"GET", "/logout/", http.StatusFound, "https://cas.example.com/cas/logout"},
+		{"GET", "//logout", http.StatusFound, "https://cas.example.com/cas/logout"},
+		{"GET", "/./logout", http.StatusFound, "https://cas.example.com/cas/logout"},
+		{"GET", "/api/../logout", http.StatusFound, "https://cas.example.com/cas/logout"},
+		{"GET", "/signout-local/", http.StatusFound, "/"},
+		{"GET", "//signout-local", http.StatusFound, "/"},
+		{"OPTIONS", "/logout", http.StatusOK, ""},
+		{"OPTIONS", "/signout-local", http.StatusOK, ""},
+	}
+
+	for _, tc := range tests {
+		r := httptest.NewRequest(tc.method, "https://service.example.com"+tc.path, nil)
+		setAuthenticationResponse(r, &AuthenticationResponse{User: "alice"})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tc.code {
+			t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.path, tc.code, w.Code)
+		}
+
+		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, tc.location) {
+			t.Errorf("%s %s: expected location starting with %q, got %q", tc.method, tc.path, tc.location, loc)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package cas
 
 import (
+	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 	"time"
@@ -23,7 +26,21 @@ const (
 	INTERNAL_ERROR             = "INTERNAL_ERROR"
 )
 
+// ServiceResponse errors
+var (
+	// The serviceResponse holds neither an authenticationSuccess nor an authenticationFailure
+	ErrEmptyServiceResponse = errors.New("cas: no authentication success or failure")
+
+	// The serviceResponse holds both an authenticationSuccess and an authenticationFailure
+	ErrAmbiguousServiceResponse = errors.New("cas: both authentication success and failure")
+
+	// The response exceeds the maximum response size
+	ErrResponseTooLarge = errors.New("cas: response exceeds maximum size")
+)
+
 // AuthenticationError represents a CAS AuthenticationFailure response
+//
+// AuthenticationError is returned by value, it matches ErrTicketRejected with errors.Is.
 type AuthenticationError struct {
 	Code    string
 	Message string
@@ -39,6 +56,11 @@ func (e AuthenticationError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// Is reports whether target is ErrTicketRejected
+func (e AuthenticationError) Is(target error) bool {
+	return target == ErrTicketRejected
+}
+
 // AuthenticationResponse captures authenticated user information
 type AuthenticationResponse struct {
 	User                string         // Users login name
@@ -49,6 +71,9 @@ type AuthenticationResponse struct {
 	IsRememberedLogin   bool           // Whether a long term token was used to grant the service ticket
 	MemberOf            []string       // List of groups which the user is a member of
 	Attributes          UserAttributes // Additional information about the user
+
+	// AttributeSources holds the attributes received in each XML style, see AttributesFrom
+	AttributeSources map[AttributeSource]UserAttributes
 }
 
 // UserAttributes represents additional data about the user
@@ -72,22 +97,103 @@ func (a UserAttributes) Add(name, value string) {
 
 // ParseServiceResponse returns a successful response or an error
 func ParseServiceResponse(data []byte) (*AuthenticationResponse, error) {
+	return NewServiceResponseDecoder(bytes.NewReader(data)).Decode()
+}
+
+// ServiceResponseDecoder reads a CAS serviceResponse document from a stream.
+type ServiceResponseDecoder struct {
+	r io.Reader
+
+	// Strict rejects documents with unknown elements in the serviceResponse,
+	// a missing user, or content following the serviceResponse.
+	Strict bool
+}
+
+// NewServiceResponseDecoder creates a ServiceResponseDecoder reading from r.
+func NewServiceResponseDecoder(r io.Reader) *ServiceResponseDecoder {
+	return &ServiceResponseDecoder{r: r}
+}
+
+// Decode reads the serviceResponse and returns a successful response or an error
+//
+// A serviceResponse holding an authenticationFailure results in an
+// AuthenticationError, any document which can not be understood results in a
+// MalformedResponseError.
+func (d *ServiceResponseDecoder) Decode() (*AuthenticationResponse, error) {
 	var x xmlServiceResponse
 
-	if err := xml.Unmarshal(data, &x); err != nil {
-		return nil, err
+	dec := xml.NewDecoder(d.r)
+	if err := dec.Decode(&x); err != nil {
+		return nil, malformedServiceResponse(err)
+	}
+
+	if x.Failure != nil && x.Success != nil {
+		return nil, malformedServiceResponse(ErrAmbiguousServiceResponse)
+	}
+
+	if x.Failure == nil && x.Success == nil {
+		return nil, malformedServiceResponse(ErrEmptyServiceResponse)
+	}
+
+	if d.Strict {
+		if err := checkStrict(&x, dec); err != nil {
+			return nil, malformedServiceResponse(err)
+		}
+	}
+
+	return x.authenticationResponse()
+}
+
+func malformedServiceResponse(err error) error {
+	return &MalformedResponseError{Response: "service response", Err: err}
+}
+
+// checkStrict verifies a decoded serviceResponse holds only known structure.
+func checkStrict(x *xmlServiceResponse, dec *xml.Decoder) error {
+	if len(x.Unknown) > 0 {
+		return fmt.Errorf("cas: unexpected element <%s>", x.Unknown[0].XMLName.Local)
+	}
+
+	if x.Success != nil && strings.TrimSpace(x.Success.User) == "" {
+		return errors.New("cas: authentication success without user")
+	}
+
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch t := t.(type) {
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+		case xml.Comment, xml.ProcInst:
+			continue
+		}
+
+		return errors.New("cas: unexpected content after serviceResponse")
 	}
+}
 
+// authenticationResponse converts the decoded serviceResponse into an
+// AuthenticationResponse or an AuthenticationError.
+func (x *xmlServiceResponse) authenticationResponse() (*AuthenticationResponse, error) {
 	if x.Failure != nil {
 		msg := strings.TrimSpace(x.Failure.Message)
-		err := &AuthenticationError{Code: x.Failure.Code, Message: msg}
-		return nil, err
+		return nil, &AuthenticationError{Code: x.Failure.Code, Message: msg}
 	}
 
 	r := &AuthenticationResponse{
 		User:                x.Success.User,
 		ProxyGrantingTicket: x.Success.ProxyGrantingTicket,
 		Attributes:          make(UserAttributes),
+		AttributeSources:    make(map[AttributeSource]UserAttributes),
 	}
 
 	if p := x.Success.Proxies; p != nil {
@@ -106,28 +212,45 @@ func ParseServiceResponse(data []byte) (*AuthenticationResponse, error) {
 					continue
 				}
 
-				r.Attributes.Add(ua.Name, strings.TrimSpace(ua.Value))
+				r.addAttribute(AttributeSourceNamed, ua.Name, strings.TrimSpace(ua.Value))
 			}
 
 			for _, ea := range a.UserAttributes.AnyAttributes {
-				r.Attributes.Add(ea.XMLName.Local, strings.TrimSpace(ea.Value))
+				r.addAttribute(AttributeSourceUserAttributes, ea.XMLName.Local, strings.TrimSpace(ea.Value))
 			}
 		}
 
 		if a.ExtraAttributes != nil {
 			for _, ea := range a.ExtraAttributes {
-				r.Attributes.Add(ea.XMLName.Local, strings.TrimSpace(ea.Value))
+				r.addAttribute(AttributeSourceAttributes, ea.XMLName.Local, strings.TrimSpace(ea.Value))
 			}
 		}
 	}
 
 	for _, ea := range x.Success.ExtraAttributes {
-		addRubycasAttribute(r.Attributes, ea.XMLName.Local, strings.TrimSpace(ea.Value))
+		values := make(UserAttributes)
+		addRubycasAttribute(values, ea.XMLName.Local, strings.TrimSpace(ea.Value))
+
+		for _, v := range values[ea.XMLName.Local] {
+			r.addAttribute(AttributeSourceRubyCAS, ea.XMLName.Local, v)
+		}
 	}
 
 	return r, nil
 }
 
+// addAttribute adds the value to the attributes, and to the attributes of the
+// XML style it was received in.
+func (r *AuthenticationResponse) addAttribute(source AttributeSource, name, value string) {
+	r.Attributes.Add(name, value)
+
+	if r.AttributeSources[source] == nil {
+		r.AttributeSources[source] = make(UserAttributes)
+	}
+
+	r.AttributeSources[source].Add(name, value)
+}
+
 // addRubycasAttribute handles RubyCAS style additional attributes.
 func addRubycasAttribute(attributes UserAttributes, key, value string) {
 	if !strings.HasPrefix(value, "---") {
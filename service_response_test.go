@@ -2,6 +2,8 @@ package cas
 
 import (
 	"encoding/xml"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -600,3 +602,77 @@ func TestSuccessfulServiceResponseWithRubyCasAttributes(t *testing.T) {
 		t.Errorf("Expected marshalled results to match. Expected:\n%s\nGot:\n%s", expected, s)
 	}
 }
+
+func TestParseEmptyServiceResponse(t *testing.T) {
+	s := `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+</cas:serviceResponse>`
+
+	_, err := ParseServiceResponse([]byte(s))
+	if !errors.Is(err, ErrEmptyServiceResponse) || !errors.Is(err, ErrMalformedResponse) {
+		t.Errorf("Expected err to be ErrEmptyServiceResponse, got <%v>", err)
+	}
+}
+
+func TestParseAmbiguousServiceResponse(t *testing.T) {
+	s := `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+    <cas:user>username</cas:user>
+  </cas:authenticationSuccess>
+  <cas:authenticationFailure code="INVALID_TICKET">Ticket not recognized</cas:authenticationFailure>
+</cas:serviceResponse>`
+
+	_, err := ParseServiceResponse([]byte(s))
+	if !errors.Is(err, ErrAmbiguousServiceResponse) || !errors.Is(err, ErrMalformedResponse) {
+		t.Errorf("Expected err to be ErrAmbiguousServiceResponse, got <%v>", err)
+	}
+}
+
+func TestDecodeServiceResponseStrict(t *testing.T) {
+	tests := map[string]string{
+		"unknown element": `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+    <cas:user>username</cas:user>
+  </cas:authenticationSuccess>
+  <cas:somethingElse/>
+</cas:serviceResponse>`,
+		"missing user": `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+  </cas:authenticationSuccess>
+</cas:serviceResponse>`,
+		"trailing content": `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+    <cas:user>username</cas:user>
+  </cas:authenticationSuccess>
+</cas:serviceResponse>
+<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas"/>`,
+	}
+
+	for name, s := range tests {
+		if _, err := ParseServiceResponse([]byte(s)); err != nil {
+			t.Errorf("%s: Expected lenient parsing to succeed, got <%v>", name, err)
+		}
+
+		d := NewServiceResponseDecoder(strings.NewReader(s))
+		d.Strict = true
+
+		if _, err := d.Decode(); err == nil {
+			t.Errorf("%s: Expected strict parsing to fail, got <nil>", name)
+		}
+	}
+
+	s := `<?xml version="1.0"?>
+<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+    <cas:user>username</cas:user>
+  </cas:authenticationSuccess>
+</cas:serviceResponse>
+<!-- generated -->
+`
+
+	d := NewServiceResponseDecoder(strings.NewReader(s))
+	d.Strict = true
+
+	if _, err := d.Decode(); err != nil {
+		t.Errorf("Expected strict parsing to succeed, got <%v>", err)
+	}
+}
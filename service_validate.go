@@ -1,54 +1,158 @@
 package cas
 
 import (
-	"fmt"
+	"bytes"
+	"context"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"path"
 
 	"github.com/golang/glog"
 )
 
+// DefaultMaxResponseSize is the largest response accepted from the cas server
+// during ticket validation when no limit is configured.
+const DefaultMaxResponseSize = 1 << 20
+
 // NewServiceTicketValidator create a new *ServiceTicketValidator
 func NewServiceTicketValidator(client *http.Client, casURL *url.URL) *ServiceTicketValidator {
 	return &ServiceTicketValidator{
-		client: client,
-		casURL: casURL,
+		client:    client,
+		endpoints: newURLEndpointPool(FailoverStrategy, nil, casURL),
+		clock:     systemClock{},
+		rand:      randOrDefault(nil),
 	}
 }
 
 // ServiceTicketValidator is responsible for the validation of a service ticket
 type ServiceTicketValidator struct {
-	client *http.Client
-	casURL *url.URL
+	client    *http.Client
+	endpoints *endpointPool
+	clock     Clock
+	rand      io.Reader
+
+	Retry          *RetryPolicy    // Optional policy for retrying validation while the cas server is unavailable
+	CircuitBreaker *CircuitBreaker // Optional breaker failing validation fast while the cas server is unavailable
+
+	MaxResponseSize int64 // Largest response accepted from the cas server, DefaultMaxResponseSize if zero
+	Strict          bool  // Reject service responses with unknown structure, see ServiceResponseDecoder
+
+	AttributeNormalizer *AttributeNormalizer // Optional normalisation of attribute names in successful responses
+
+	RequestMutator    RequestMutator    // Optional hook modifying requests to the cas server
+	ResponseInspector ResponseInspector // Optional hook observing responses from the cas server
 }
 
 // ValidateTicket validates the service ticket for the given server. The method will try to use the service validate
 // endpoint of the cas >= 2 protocol, if the service validate endpoint not available, the function will use the cas 1
 // validate endpoint.
 func (validator *ServiceTicketValidator) ValidateTicket(serviceURL *url.URL, ticket string) (*AuthenticationResponse, error) {
+	return validator.ValidateTicketContext(context.Background(), serviceURL, ticket)
+}
+
+// ValidateTicketContext is ValidateTicket with a context controlling the lifetime of the requests to the cas server.
+func (validator *ServiceTicketValidator) ValidateTicketContext(ctx context.Context, serviceURL *url.URL, ticket string) (*AuthenticationResponse, error) {
 	if glog.V(2) {
 		glog.Infof("Validating ticket %v for service %v", ticket, serviceURL)
 	}
 
-	u, err := validator.ServiceValidateUrl(serviceURL, ticket)
+	success, err := validator.withRetry(ctx, func(ctx context.Context) (*AuthenticationResponse, error) {
+		return validator.withFailover(ctx, func(ctx context.Context, e *endpoint) (*AuthenticationResponse, error) {
+			return validator.validateTicket(ctx, e.scheme, serviceURL, ticket)
+		})
+	})
+
 	if err != nil {
 		return nil, err
 	}
 
-	r, err := http.NewRequest("GET", u, nil)
+	validator.AttributeNormalizer.Normalize(success)
+	return success, nil
+}
+
+// withRetry performs the validation call, guarded by the circuit breaker and
+// retried according to the retry policy.
+func (validator *ServiceTicketValidator) withRetry(ctx context.Context, call func(context.Context) (*AuthenticationResponse, error)) (*AuthenticationResponse, error) {
+	attempts := 1
+	if validator.Retry != nil && validator.Retry.MaxAttempts > 1 {
+		attempts = validator.Retry.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		if b := validator.CircuitBreaker; b != nil {
+			if err := b.Allow(); err != nil {
+				return nil, err
+			}
+		}
+
+		success, err := call(ctx)
+
+		if b := validator.CircuitBreaker; b != nil {
+			if isRetryable(err) {
+				b.Failure()
+			} else if ctx.Err() == nil {
+				b.Success()
+			}
+		}
+
+		if !isRetryable(err) || attempt >= attempts {
+			return success, err
+		}
+
+		if glog.V(2) {
+			glog.Infof("Retrying ticket validation after attempt %d failed: %v", attempt, err)
+		}
+
+		if err := sleep(ctx, validator.clock, validator.Retry.backoff(attempt, validator.rand)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// withFailover performs the validation call against each cas server in turn,
+// until one of them is able to answer.
+func (validator *ServiceTicketValidator) withFailover(ctx context.Context, call func(context.Context, *endpoint) (*AuthenticationResponse, error)) (*AuthenticationResponse, error) {
+	var err error
+	for _, e := range validator.endpoints.candidates() {
+		var success *AuthenticationResponse
+		success, err = call(ctx, e)
+
+		if !isRetryable(err) {
+			if ctx.Err() == nil {
+				validator.endpoints.markSuccess(e)
+			}
+
+			return success, err
+		}
+
+		validator.endpoints.markFailure(e, err)
+
+		if glog.V(2) {
+			glog.Infof("Ticket validation failed, trying next cas server: %v", err)
+		}
+	}
+
+	return nil, err
+}
+
+// validateTicket performs a single validation of the service ticket against the cas server.
+func (validator *ServiceTicketValidator) validateTicket(ctx context.Context, scheme URLScheme, serviceURL *url.URL, ticket string) (*AuthenticationResponse, error) {
+	u, err := ticketURL(scheme.ServiceValidate, serviceURL, ticket)
 	if err != nil {
 		return nil, err
 	}
 
-	r.Header.Add("User-Agent", "Golang CAS client gopkg.in/cas")
+	r, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	if glog.V(2) {
 		glog.Infof("Attempting ticket validation with %v", r.URL)
 	}
 
-	resp, err := validator.client.Do(r)
+	resp, err := sendRequest(validator.client, "validate ticket", r, validator.RequestMutator, validator.ResponseInspector)
 	if err != nil {
 		return nil, err
 	}
@@ -60,25 +164,36 @@ func (validator *ServiceTicketValidator) ValidateTicket(serviceURL *url.URL, tic
 	}
 
 	if resp.StatusCode == http.StatusNotFound {
-		return validator.validateTicketCas1(serviceURL, ticket)
+		resp.Body.Close()
+		return validator.validateTicketCas1(ctx, scheme, serviceURL, ticket)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
+	defer resp.Body.Close()
+	body := validator.limitReader(resp.Body)
 
-	if err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, &StatusError{Op: "validate ticket", URL: u, StatusCode: resp.StatusCode, Body: string(data)}
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("cas: validate ticket: %v", string(body))
+	var logged bytes.Buffer
+	if glog.V(2) {
+		body = io.TeeReader(body, &logged)
 	}
 
+	decoder := NewServiceResponseDecoder(body)
+	decoder.Strict = validator.Strict
+
+	success, err := decoder.Decode()
+
 	if glog.V(2) {
-		glog.Infof("Received authentication response\n%v", string(body))
+		glog.Infof("Received authentication response\n%v", logged.String())
 	}
 
-	success, err := ParseServiceResponse(body)
 	if err != nil {
 		return nil, err
 	}
@@ -93,37 +208,25 @@ func (validator *ServiceTicketValidator) ValidateTicket(serviceURL *url.URL, tic
 // ServiceValidateUrl creates the service validation url for the cas >= 2 protocol.
 // TODO the function is only exposed, because of the clients ServiceValidateUrl function
 func (validator *ServiceTicketValidator) ServiceValidateUrl(serviceURL *url.URL, ticket string) (string, error) {
-	u, err := validator.casURL.Parse(path.Join(validator.casURL.Path, "serviceValidate"))
-	if err != nil {
-		return "", err
-	}
-
-	q := u.Query()
-	q.Add("service", sanitisedURLString(serviceURL))
-	q.Add("ticket", ticket)
-	u.RawQuery = q.Encode()
-
-	return u.String(), nil
+	return ticketURL(validator.endpoints.primary().scheme.ServiceValidate, serviceURL, ticket)
 }
 
-func (validator *ServiceTicketValidator) validateTicketCas1(serviceURL *url.URL, ticket string) (*AuthenticationResponse, error) {
-	u, err := validator.ValidateUrl(serviceURL, ticket)
+func (validator *ServiceTicketValidator) validateTicketCas1(ctx context.Context, scheme URLScheme, serviceURL *url.URL, ticket string) (*AuthenticationResponse, error) {
+	u, err := ticketURL(scheme.Validate, serviceURL, ticket)
 	if err != nil {
 		return nil, err
 	}
 
-	r, err := http.NewRequest("GET", u, nil)
+	r, err := http.NewRequestWithContext(ctx, "GET", u, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	r.Header.Add("User-Agent", "Golang CAS client gopkg.in/cas")
-
 	if glog.V(2) {
 		glog.Infof("Attempting ticket validation with %v", r.URL)
 	}
 
-	resp, err := validator.client.Do(r)
+	resp, err := sendRequest(validator.client, "validate ticket", r, validator.RequestMutator, validator.ResponseInspector)
 	if err != nil {
 		return nil, err
 	}
@@ -134,7 +237,7 @@ func (validator *ServiceTicketValidator) validateTicketCas1(serviceURL *url.URL,
 			resp.Status)
 	}
 
-	data, err := ioutil.ReadAll(resp.Body)
+	data, err := ioutil.ReadAll(validator.limitReader(resp.Body))
 	resp.Body.Close()
 
 	if err != nil {
@@ -144,19 +247,16 @@ func (validator *ServiceTicketValidator) validateTicketCas1(serviceURL *url.URL,
 	body := string(data)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("cas: validate ticket: %v", body)
+		return nil, &StatusError{Op: "validate ticket", URL: u, StatusCode: resp.StatusCode, Body: body}
 	}
 
 	if glog.V(2) {
 		glog.Infof("Received authentication response\n%v", body)
 	}
 
-	if body == "no\n\n" {
-		return nil, nil // not logged in
-	}
-
-	success := &AuthenticationResponse{
-		User: body[4 : len(body)-1],
+	success, err := parseCas1Response(body)
+	if err != nil {
+		return nil, err
 	}
 
 	if glog.V(2) {
@@ -169,7 +269,12 @@ func (validator *ServiceTicketValidator) validateTicketCas1(serviceURL *url.URL,
 // ValidateUrl creates the validation url for the cas >= 1 protocol.
 // TODO the function is only exposed, because of the clients ValidateUrl function
 func (validator *ServiceTicketValidator) ValidateUrl(serviceURL *url.URL, ticket string) (string, error) {
-	u, err := validator.casURL.Parse(path.Join(validator.casURL.Path, "validate"))
+	return ticketURL(validator.endpoints.primary().scheme.Validate, serviceURL, ticket)
+}
+
+// ticketURL adds the service and ticket parameters to the url of a validation endpoint.
+func ticketURL(endpoint func() (*url.URL, error), serviceURL *url.URL, ticket string) (string, error) {
+	u, err := endpoint()
 	if err != nil {
 		return "", err
 	}
@@ -181,3 +286,40 @@ func (validator *ServiceTicketValidator) ValidateUrl(serviceURL *url.URL, ticket
 
 	return u.String(), nil
 }
+
+// limitReader limits r to the maximum response size, reading beyond it fails
+// with ErrResponseTooLarge.
+func (validator *ServiceTicketValidator) limitReader(r io.Reader) io.Reader {
+	max := validator.MaxResponseSize
+	if max <= 0 {
+		max = DefaultMaxResponseSize
+	}
+
+	return &limitedReader{r: r, n: max}
+}
+
+// limitedReader is an io.LimitedReader which fails instead of reporting EOF
+// once the limit is exceeded.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrResponseTooLarge
+	}
+
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+
+	if l.n < 0 {
+		return n - int(-l.n), ErrResponseTooLarge
+	}
+
+	return n, err
+}
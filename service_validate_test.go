@@ -0,0 +1,243 @@
+package cas
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidateTicketContextCancelled(t *testing.T) {
+	server := &TestServer{}
+	defer server.Close()
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	casURL, _ := url.Parse(ts.URL)
+	serviceURL, _ := url.Parse("http://example.com/")
+	validator := NewServiceTicketValidator(ts.Client(), casURL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := validator.ValidateTicketContext(ctx, serviceURL, "ST-1")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error to be context.Canceled, got %v", err)
+	}
+}
+
+func TestValidateTicketUsesRequestContext(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	casURL, _ := url.Parse(ts.URL)
+	client := NewClient(&Options{
+		URL:    casURL,
+		Client: ts.Client(),
+	})
+
+	handler := client.HandleFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsAuthenticated(r) {
+			t.Errorf("Expected request to be unauthenticated")
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/?ticket=ST-1", nil)
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected ticket validation to stop at the request deadline")
+	}
+}
+
+func TestValidateTicketRetry(t *testing.T) {
+	server, ticket := newTestServerWithTicket()
+	defer server.Close()
+
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		server.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	casURL, _ := url.Parse(ts.URL)
+	serviceURL, _ := url.Parse("http://example.com/")
+	validator := NewServiceTicketValidator(ts.Client(), casURL)
+	validator.Retry = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	success, err := validator.ValidateTicket(serviceURL, ticket.Name)
+	if err != nil {
+		t.Fatalf("Expected ValidateTicket to succeed, got %v", err)
+	}
+
+	if success.User != "enoch.root" {
+		t.Errorf("Expected User to be <enoch.root>, got <%v>", success.User)
+	}
+
+	if calls != 3 {
+		t.Errorf("Expected 3 validation requests, got %v", calls)
+	}
+
+	// rejected tickets are not retried
+	atomic.StoreInt32(&calls, 2)
+	if _, err := validator.ValidateTicket(serviceURL, "ST-2"); err == nil {
+		t.Errorf("Expected ValidateTicket to fail for ST-2")
+	}
+
+	if calls != 3 {
+		t.Errorf("Expected a single validation request, got %v", calls-2)
+	}
+}
+
+func TestValidateTicketCircuitBreaker(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	casURL, _ := url.Parse(ts.URL)
+	serviceURL, _ := url.Parse("http://example.com/")
+	validator := NewServiceTicketValidator(ts.Client(), casURL)
+	validator.Retry = &RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond}
+	validator.CircuitBreaker = NewCircuitBreaker(&BreakerOptions{FailureThreshold: 2})
+
+	_, err := validator.ValidateTicket(serviceURL, "ST-1")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected error to be ErrCircuitOpen, got %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected 2 validation requests, got %v", calls)
+	}
+
+	if s := validator.CircuitBreaker.State(); s != BreakerOpen {
+		t.Errorf("Expected breaker to be open, got %v", s)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond}
+
+	for retry, max := range []time.Duration{10, 20, 40, 40} {
+		for i := 0; i < 100; i++ {
+			if d := p.backoff(retry+1, rand.Reader); d < 0 || d > max*time.Millisecond {
+				t.Fatalf("Expected backoff for retry %d to be within [0, %v], got %v", retry+1, max*time.Millisecond, d)
+			}
+		}
+	}
+}
+
+func TestValidateTicketResponseTooLarge(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas"><cas:authenticationSuccess><cas:user>`)
+		fmt.Fprint(w, strings.Repeat("a", 4096))
+		fmt.Fprint(w, `</cas:user></cas:authenticationSuccess></cas:serviceResponse>`)
+	}))
+	defer ts.Close()
+
+	casURL, _ := url.Parse(ts.URL)
+	serviceURL, _ := url.Parse("http://example.com/")
+	validator := NewServiceTicketValidator(ts.Client(), casURL)
+	validator.MaxResponseSize = 1024
+
+	_, err := validator.ValidateTicket(serviceURL, "ST-1")
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Expected error to be ErrResponseTooLarge, got %v", err)
+	}
+
+	validator.MaxResponseSize = 0
+
+	success, err := validator.ValidateTicket(serviceURL, "ST-1")
+	if err != nil {
+		t.Fatalf("Expected ValidateTicket to succeed, got %v", err)
+	}
+
+	if len(success.User) != 4096 {
+		t.Errorf("Expected User to have 4096 characters, got %v", len(success.User))
+	}
+}
+
+func TestRetryUsesClockAndRandomness(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	clock := newTestClock()
+	start := clock.Now()
+
+	var entropy bytes.Buffer
+	binary.Write(&entropy, binary.BigEndian, uint64(30*time.Minute))
+	binary.Write(&entropy, binary.BigEndian, uint64(90*time.Minute))
+
+	casURL, _ := url.Parse(ts.URL)
+	serviceURL, _ := url.Parse("http://example.com/")
+	validator := NewServiceTicketValidator(ts.Client(), casURL)
+	validator.Retry = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour, MaxBackoff: 4 * time.Hour}
+	validator.clock = clock
+	validator.rand = &entropy
+
+	done := make(chan error)
+	go func() {
+		_, err := validator.ValidateTicket(serviceURL, "ST-1")
+		done <- err
+	}()
+
+	var err error
+wait:
+	for {
+		select {
+		case err = <-done:
+			break wait
+		case <-time.After(time.Millisecond):
+			if clock.Waiters() > 0 {
+				clock.Advance(time.Minute)
+			}
+		}
+	}
+
+	if !errors.Is(err, ErrUnexpectedStatus) {
+		t.Errorf("Expected error matching ErrUnexpectedStatus, got %v", err)
+	}
+
+	if calls != 3 {
+		t.Errorf("Expected 3 validation requests, got %v", calls)
+	}
+
+	if waited := clock.Now().Sub(start); waited != 2*time.Hour {
+		t.Errorf("Expected retries to wait 2h on the clock, got %v", waited)
+	}
+}
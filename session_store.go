@@ -1,6 +1,18 @@
 package cas
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// SessionStore errors
+var (
+	// Given session id is not associated with a ticket
+	ErrInvalidSession = errors.New("cas: session store: invalid session")
+)
 
 // SessionStore store the session's ticket
 // SessionID is retrived from cookies
@@ -15,29 +27,60 @@ type SessionStore interface {
 	Delete(sessionID string) error
 }
 
+// ContextSessionStore is a SessionStore which honours request cancellation and
+// deadlines, and is able to report lookup errors. The Client prefers the context
+// aware methods when the configured SessionStore implements them.
+type ContextSessionStore interface {
+	SessionStore
+
+	// GetContext returns the ticket for the session id, or ErrInvalidSession
+	// if the session is unknown.
+	GetContext(ctx context.Context, sessionID string) (string, error)
+
+	// SetContext associates the session with a ticket
+	SetContext(ctx context.Context, sessionID, ticket string) error
+
+	// DeleteContext removes the session
+	DeleteContext(ctx context.Context, sessionID string) error
+}
+
 // NewMemorySessionStore create a default SessionStore that uses memory
+//
+// The returned SessionStore also implements Snapshotter.
 func NewMemorySessionStore() SessionStore {
+	return newMemorySessionStore(nil)
+}
+
+// newMemorySessionStore creates a memory SessionStore timestamping entries with clock
+func newMemorySessionStore(clock Clock) *memorySessionStore {
 	return &memorySessionStore{
-		sessions: make(map[string]string),
+		clock:    clockOrDefault(clock),
+		sessions: make(map[string]memorySession),
 	}
 }
 
 type memorySessionStore struct {
+	clock    Clock
 	mu       sync.RWMutex
-	sessions map[string]string
+	sessions map[string]memorySession
+}
+
+type memorySession struct {
+	ticket  string
+	written time.Time
 }
 
 func (m *memorySessionStore) Get(sessionID string) (string, bool) {
 	m.mu.RLock()
-	ticket, ok := m.sessions[sessionID]
+	s, ok := m.sessions[sessionID]
 	m.mu.RUnlock()
 
-	return ticket, ok
+	return s.ticket, ok
 }
 
 func (m *memorySessionStore) Set(sessionID, ticket string) error {
 	m.mu.Lock()
-	m.sessions[sessionID] = ticket
+	m.sessions[sessionID] = memorySession{ticket: ticket, written: m.clock.Now()}
 	m.mu.Unlock()
 
 	return nil
@@ -50,3 +93,40 @@ func (m *memorySessionStore) Delete(sessionID string) error {
 
 	return nil
 }
+
+func (m *memorySessionStore) Snapshot(w io.Writer) error {
+	snap := &snapshot{Kind: sessionSnapshotKind}
+
+	m.mu.RLock()
+	for id, s := range m.sessions {
+		snap.Sessions = append(snap.Sessions, sessionEntry{
+			ID:      id,
+			Written: s.written,
+			Ticket:  s.ticket,
+		})
+	}
+	m.mu.RUnlock()
+
+	return writeSnapshot(w, snap)
+}
+
+func (m *memorySessionStore) Restore(r io.Reader, maxAge time.Duration) error {
+	snap, err := readSnapshot(r, sessionSnapshotKind)
+	if err != nil {
+		return err
+	}
+
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	for _, e := range snap.Sessions {
+		if expired(e.Written, now, maxAge) {
+			continue
+		}
+
+		m.sessions[e.ID] = memorySession{ticket: e.Ticket, written: e.Written}
+	}
+	m.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package cas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// snapshotVersion is the version of the snapshot format written by Snapshot.
+const snapshotVersion = 1
+
+// Snapshot kinds
+const (
+	ticketSnapshotKind  = "tickets"
+	sessionSnapshotKind = "sessions"
+)
+
+// Snapshotter is implemented by the in-memory stores, allowing their contents
+// to be carried across a restart.
+type Snapshotter interface {
+	// Snapshot writes all entries held by the store to w.
+	Snapshot(w io.Writer) error
+
+	// Restore reads entries written by Snapshot from r into the store. Entries
+	// older than maxAge are skipped, a maxAge of zero restores every entry.
+	Restore(r io.Reader, maxAge time.Duration) error
+}
+
+// snapshot is the serialised form of a store.
+type snapshot struct {
+	Version  int            `json:"version"`
+	Kind     string         `json:"kind"`
+	Tickets  []ticketEntry  `json:"tickets,omitempty"`
+	Sessions []sessionEntry `json:"sessions,omitempty"`
+}
+
+// ticketEntry is a snapshotted MemoryStore entry
+type ticketEntry struct {
+	ID       string                  `json:"id"`
+	Written  time.Time               `json:"written"`
+	Response *AuthenticationResponse `json:"response"`
+}
+
+// sessionEntry is a snapshotted memory session store entry
+type sessionEntry struct {
+	ID      string    `json:"id"`
+	Written time.Time `json:"written"`
+	Ticket  string    `json:"ticket"`
+}
+
+func writeSnapshot(w io.Writer, s *snapshot) error {
+	s.Version = snapshotVersion
+	return json.NewEncoder(w).Encode(s)
+}
+
+func readSnapshot(r io.Reader, kind string) (*snapshot, error) {
+	s := &snapshot{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, fmt.Errorf("cas: snapshot: %v", err)
+	}
+
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("cas: snapshot: unsupported version %d", s.Version)
+	}
+
+	if s.Kind != kind {
+		return nil, fmt.Errorf("cas: snapshot: expected %s snapshot, got %q", kind, s.Kind)
+	}
+
+	return s, nil
+}
+
+// expired reports whether an entry written at the given time is older than maxAge.
+func expired(written, now time.Time, maxAge time.Duration) bool {
+	return maxAge > 0 && now.Sub(written) > maxAge
+}
@@ -0,0 +1,91 @@
+package cas
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreSnapshot(t *testing.T) {
+	clock := newTestClock()
+	store := &MemoryStore{Clock: clock}
+
+	user := &AuthenticationResponse{
+		User:       "user1",
+		MemberOf:   []string{"Group1"},
+		Attributes: UserAttributes{"email": []string{"user1@example.org"}},
+	}
+
+	if err := store.Write("ST-2", &AuthenticationResponse{User: "user2"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	clock.Advance(2 * time.Hour)
+
+	if err := store.Write("ST-1", user); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := store.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	restored := &MemoryStore{Clock: clock}
+	if err := restored.Restore(&buf, time.Hour); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if ar, err := restored.Read("ST-1"); err != nil || !reflect.DeepEqual(user, ar) {
+		t.Errorf("Expected %v to be restored, got %v, %v", user, ar, err)
+	}
+
+	if _, err := restored.Read("ST-2"); err != ErrInvalidTicket {
+		t.Errorf("Expected entry older than the max age to be dropped, got %v", err)
+	}
+}
+
+func TestMemorySessionStoreSnapshot(t *testing.T) {
+	clock := newTestClock()
+	ss := newMemorySessionStore(clock)
+
+	if err := ss.Set("key2", "ST-2"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	clock.Advance(2 * time.Hour)
+
+	if err := ss.Set("key1", "ST-1"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ss.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	restored := newMemorySessionStore(clock)
+	if err := restored.Restore(&buf, time.Hour); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if v, ok := restored.Get("key1"); !ok || v != "ST-1" {
+		t.Errorf("Expected key1 to be restored, got %q, %v", v, ok)
+	}
+
+	if _, ok := restored.Get("key2"); ok {
+		t.Errorf("Expected entry older than the max age to be dropped")
+	}
+}
+
+func TestRestoreRejectsWrongKind(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewMemorySessionStore().(Snapshotter).Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	if err := (&MemoryStore{}).Restore(&buf, 0); err == nil {
+		t.Errorf("Expected session snapshot to be rejected by the ticket store")
+	}
+}
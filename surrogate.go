@@ -0,0 +1,102 @@
+package cas
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Names of the attributes released by Apereo CAS for surrogate authentication
+const (
+	SurrogateEnabledAttribute   = "surrogateEnabled"
+	SurrogateUserAttribute      = "surrogateUser"
+	SurrogatePrincipalAttribute = "surrogatePrincipal"
+)
+
+// IsImpersonated indicates whether the user was authenticated by someone else
+// using CAS surrogate authentication.
+func (a *AuthenticationResponse) IsImpersonated() bool {
+	_, _, ok := a.surrogate()
+	return ok
+}
+
+// RealUsername returns the login name of the person who authenticated, which
+// differs from User while impersonating. It is empty if the CAS server reported
+// an impersonated login without the surrogate principal.
+func (a *AuthenticationResponse) RealUsername() string {
+	if principal, _, ok := a.surrogate(); ok {
+		return principal
+	}
+
+	return a.User
+}
+
+// ImpersonatedUsername returns the login name of the impersonated user, or an
+// empty string if the user is not impersonated.
+func (a *AuthenticationResponse) ImpersonatedUsername() string {
+	_, user, ok := a.surrogate()
+	if !ok {
+		return ""
+	}
+
+	if user != "" {
+		return user
+	}
+
+	return a.User
+}
+
+// surrogate returns the surrogate principal and user attributes, ignoring the
+// case of their names. ok is false unless the user is impersonated, which is
+// decided by surrogateEnabled alone so a missing principal fails closed.
+func (a *AuthenticationResponse) surrogate() (principal, user string, ok bool) {
+	enabled, err := strconv.ParseBool(strings.TrimSpace(a.Attributes.getFold(SurrogateEnabledAttribute)))
+	if err != nil || !enabled {
+		return "", "", false
+	}
+
+	principal = a.Attributes.getFold(SurrogatePrincipalAttribute)
+	return principal, a.Attributes.getFold(SurrogateUserAttribute), true
+}
+
+// IsImpersonated indicates whether the authenticated user is impersonated
+// using CAS surrogate authentication.
+func IsImpersonated(r *http.Request) bool {
+	if a := getAuthenticationResponse(r); a != nil {
+		return a.IsImpersonated()
+	}
+
+	return false
+}
+
+// RealUsername returns the login name of the person who authenticated. Use it
+// in audit trails, Username returns the impersonated user.
+func RealUsername(r *http.Request) string {
+	if a := getAuthenticationResponse(r); a != nil {
+		return a.RealUsername()
+	}
+
+	return ""
+}
+
+// ImpersonatedUsername returns the login name of the impersonated user, or an
+// empty string if the user is not impersonated.
+func ImpersonatedUsername(r *http.Request) string {
+	if a := getAuthenticationResponse(r); a != nil {
+		return a.ImpersonatedUsername()
+	}
+
+	return ""
+}
+
+// NotImpersonated returns a Rule satisfied by users who are not impersonated
+func NotImpersonated() Rule {
+	return func(a *AuthenticationResponse) bool {
+		return !a.IsImpersonated()
+	}
+}
+
+// DenyImpersonated returns Middleware denying access to impersonated users
+func DenyImpersonated() Middleware {
+	return Require(NotImpersonated())
+}
@@ -0,0 +1,107 @@
+package cas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestSurrogateAuthentication(t *testing.T) {
+	s := `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+    <cas:user>casuser</cas:user>
+    <cas:attributes>
+      <cas:surrogateEnabled>true</cas:surrogateEnabled>
+      <cas:surrogateUser>casuser</cas:surrogateUser>
+      <cas:surrogatePrincipal>helpdesk</cas:surrogatePrincipal>
+    </cas:attributes>
+  </cas:authenticationSuccess>
+</cas:serviceResponse>`
+
+	a, err := ParseServiceResponse([]byte(s))
+	if err != nil {
+		t.Fatalf("ParseServiceResponse failed: %v", err)
+	}
+
+	r := httptest.NewRequest("GET", "https://service.example.com/", nil)
+	setAuthenticationResponse(r, a)
+
+	if !IsImpersonated(r) {
+		t.Errorf("Expected request to be impersonated")
+	}
+
+	if u := RealUsername(r); u != "helpdesk" {
+		t.Errorf("Expected real username helpdesk, got %q", u)
+	}
+
+	if u := ImpersonatedUsername(r); u != "casuser" {
+		t.Errorf("Expected impersonated username casuser, got %q", u)
+	}
+
+	if u := Username(r); u != "casuser" {
+		t.Errorf("Expected username casuser, got %q", u)
+	}
+
+	if !MustCompilePolicy(`impersonated && realUser == "helpdesk"`).Evaluate(a) {
+		t.Errorf("Expected policy to see impersonation")
+	}
+}
+
+func TestNotImpersonated(t *testing.T) {
+	plain := &AuthenticationResponse{User: "alice", Attributes: UserAttributes{}}
+	disabled := &AuthenticationResponse{User: "alice", Attributes: UserAttributes{
+		"surrogateEnabled":   {"false"},
+		"surrogatePrincipal": {"helpdesk"},
+	}}
+
+	for _, a := range []*AuthenticationResponse{plain, disabled} {
+		if a.IsImpersonated() || a.RealUsername() != "alice" || a.ImpersonatedUsername() != "" {
+			t.Errorf("Expected %v not to be impersonated", a.Attributes)
+		}
+	}
+
+	casURL, _ := url.Parse("https://cas.example.com/cas/")
+	client := NewClient(&Options{URL: casURL})
+	handler := DenyImpersonated()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	impersonated := &AuthenticationResponse{User: "alice", Attributes: UserAttributes{
+		"SurrogateEnabled":   {"true"},
+		"SurrogatePrincipal": {"helpdesk"},
+	}}
+
+	for a, code := range map[*AuthenticationResponse]int{plain: http.StatusOK, impersonated: http.StatusForbidden} {
+		r := httptest.NewRequest("GET", "https://service.example.com/billing", nil)
+		setClient(r, client)
+		setAuthenticationResponse(r, a)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != code {
+			t.Errorf("Expected status %d, got %d", code, w.Code)
+		}
+	}
+}
+
+func TestImpersonatedWithoutPrincipal(t *testing.T) {
+	a := &AuthenticationResponse{User: "alice", Attributes: UserAttributes{
+		"surrogateEnabled": {"true"},
+	}}
+
+	if !a.IsImpersonated() {
+		t.Errorf("Expected surrogateEnabled without principal to be impersonated")
+	}
+
+	if u := a.RealUsername(); u != "" {
+		t.Errorf("Expected unknown real username, got %q", u)
+	}
+
+	if u := a.ImpersonatedUsername(); u != "alice" {
+		t.Errorf("Expected impersonated username alice, got %q", u)
+	}
+
+	if NotImpersonated()(a) {
+		t.Errorf("Expected NotImpersonated to deny the session")
+	}
+}
@@ -1,6 +1,7 @@
 package cas
 
 import (
+	"context"
 	"errors"
 )
 
@@ -25,3 +26,22 @@ type TicketStore interface {
 	// Clear removes all of the AuthenticationResponse data from the store.
 	Clear() error
 }
+
+// ContextTicketStore is a TicketStore which honours request cancellation and
+// deadlines. The Client prefers the context aware methods when the configured
+// TicketStore implements them.
+type ContextTicketStore interface {
+	TicketStore
+
+	// ReadContext returns the AuthenticationResponse data associated with a ticket identifier.
+	ReadContext(ctx context.Context, id string) (*AuthenticationResponse, error)
+
+	// WriteContext stores the AuthenticationResponse data received from a ticket validation.
+	WriteContext(ctx context.Context, id string, ticket *AuthenticationResponse) error
+
+	// DeleteContext removes the AuthenticationResponse data associated with a ticket identifier.
+	DeleteContext(ctx context.Context, id string) error
+
+	// ClearContext removes all of the AuthenticationResponse data from the store.
+	ClearContext(ctx context.Context) error
+}
@@ -0,0 +1,168 @@
+package cas
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetAll returns every value of the attribute
+func (a UserAttributes) GetAll(name string) []string {
+	return a[name]
+}
+
+// Has indicates whether the attribute has at least one value
+func (a UserAttributes) Has(name string) bool {
+	return len(a[name]) > 0
+}
+
+// GetBool parses the first value of the attribute as a bool
+func (a UserAttributes) GetBool(name string) (bool, error) {
+	v, err := a.first(name)
+	if err != nil {
+		return false, err
+	}
+
+	return strconv.ParseBool(v)
+}
+
+// GetInt parses the first value of the attribute as an int
+func (a UserAttributes) GetInt(name string) (int, error) {
+	v, err := a.first(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(v)
+}
+
+// GetTime parses the first value of the attribute as a time. RFC 3339 dates,
+// with or without a Java zone id suffix, LDAP generalized time and RubyCAS
+// style dates are accepted.
+func (a UserAttributes) GetTime(name string) (time.Time, error) {
+	v, err := a.first(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return parseAttributeTime(v)
+}
+
+// first returns the trimmed first value of the attribute, or ErrMissingAttribute
+func (a UserAttributes) first(name string) (string, error) {
+	v := a[name]
+	if len(v) == 0 {
+		return "", ErrMissingAttribute
+	}
+
+	return strings.TrimSpace(v[0]), nil
+}
+
+// getFold returns the first value of the attribute, ignoring the case of its
+// name if there is no exact match.
+func (a UserAttributes) getFold(name string) string {
+	if v := a[name]; len(v) > 0 {
+		return v[0]
+	}
+
+	for n, v := range a {
+		if len(v) > 0 && strings.EqualFold(n, name) {
+			return v[0]
+		}
+	}
+
+	return ""
+}
+
+// Folded returns a case-insensitive view of the attributes. Values of names
+// differing only in case are merged.
+func (a UserAttributes) Folded() FoldedAttributes {
+	folded := make(UserAttributes, len(a))
+	for name, values := range a {
+		key := strings.ToLower(name)
+		folded[key] = append(folded[key], values...)
+	}
+
+	return FoldedAttributes{attributes: folded}
+}
+
+// FoldedAttributes looks up attributes ignoring the case of their names
+type FoldedAttributes struct {
+	attributes UserAttributes
+}
+
+// Get retrieves the first value of the attribute
+func (f FoldedAttributes) Get(name string) string {
+	return f.attributes.Get(strings.ToLower(name))
+}
+
+// GetAll returns every value of the attribute
+func (f FoldedAttributes) GetAll(name string) []string {
+	return f.attributes.GetAll(strings.ToLower(name))
+}
+
+// Has indicates whether the attribute has at least one value
+func (f FoldedAttributes) Has(name string) bool {
+	return f.attributes.Has(strings.ToLower(name))
+}
+
+// GetBool parses the first value of the attribute as a bool
+func (f FoldedAttributes) GetBool(name string) (bool, error) {
+	return f.attributes.GetBool(strings.ToLower(name))
+}
+
+// GetInt parses the first value of the attribute as an int
+func (f FoldedAttributes) GetInt(name string) (int, error) {
+	return f.attributes.GetInt(strings.ToLower(name))
+}
+
+// GetTime parses the first value of the attribute as a time, see UserAttributes.GetTime
+func (f FoldedAttributes) GetTime(name string) (time.Time, error) {
+	return f.attributes.GetTime(strings.ToLower(name))
+}
+
+// AttributeSource identifies the XML style an attribute was received in
+type AttributeSource int
+
+// AttributeSource values
+const (
+	// AttributeSourceAttributes is a child of cas:attributes, as sent by CAS 3.
+	AttributeSourceAttributes AttributeSource = iota + 1
+
+	// AttributeSourceNamed is a cas:attribute element with a name attribute
+	// within cas:userAttributes.
+	AttributeSourceNamed
+
+	// AttributeSourceUserAttributes is a child of cas:userAttributes.
+	AttributeSourceUserAttributes
+
+	// AttributeSourceRubyCAS is a child of cas:authenticationSuccess, as sent by RubyCAS.
+	AttributeSourceRubyCAS
+)
+
+// String returns the name of the AttributeSource
+func (s AttributeSource) String() string {
+	switch s {
+	case AttributeSourceAttributes:
+		return "attributes"
+	case AttributeSourceNamed:
+		return "named"
+	case AttributeSourceUserAttributes:
+		return "userAttributes"
+	case AttributeSourceRubyCAS:
+		return "rubycas"
+	default:
+		return "unknown"
+	}
+}
+
+// AttributesFrom returns the attributes received in the XML style of source.
+// An attribute sent in several styles is returned for each of them, with the
+// values sent in that style.
+func (r *AuthenticationResponse) AttributesFrom(source AttributeSource) UserAttributes {
+	if attributes := r.AttributeSources[source]; attributes != nil {
+		return attributes
+	}
+
+	return make(UserAttributes)
+}
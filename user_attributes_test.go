@@ -0,0 +1,139 @@
+package cas
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUserAttributesAccessors(t *testing.T) {
+	a := UserAttributes{
+		"mail":      {"alice@example.com", "a@example.com"},
+		"isAdmin":   {"true"},
+		"uidNumber": {" 1001 "},
+		"created":   {"2026-10-19T08:30:00Z"},
+		"junk":      {"nope"},
+	}
+
+	if v := a.GetAll("mail"); !reflect.DeepEqual(v, []string{"alice@example.com", "a@example.com"}) {
+		t.Errorf("Unexpected GetAll result %v", v)
+	}
+
+	if !a.Has("mail") || a.Has("phone") {
+		t.Errorf("Unexpected Has results")
+	}
+
+	if b, err := a.GetBool("isAdmin"); err != nil || !b {
+		t.Errorf("GetBool = %v, %v", b, err)
+	}
+
+	if n, err := a.GetInt("uidNumber"); err != nil || n != 1001 {
+		t.Errorf("GetInt = %v, %v", n, err)
+	}
+
+	if tm, err := a.GetTime("created"); err != nil || !tm.Equal(time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("GetTime = %v, %v", tm, err)
+	}
+
+	if _, err := a.GetInt("junk"); err == nil {
+		t.Errorf("Expected invalid int to fail")
+	}
+
+	if _, err := a.GetBool("phone"); !errors.Is(err, ErrMissingAttribute) {
+		t.Errorf("Expected ErrMissingAttribute, got %v", err)
+	}
+}
+
+func TestUserAttributesFolded(t *testing.T) {
+	a := UserAttributes{
+		"Mail":    {"alice@example.com"},
+		"mail":    {"a@example.com"},
+		"IsAdmin": {"false"},
+	}
+
+	f := a.Folded()
+
+	if len(f.GetAll("MAIL")) != 2 {
+		t.Errorf("Expected merged values for mail, got %v", f.GetAll("MAIL"))
+	}
+
+	if !f.Has("isadmin") || f.Get("ISADMIN") != "false" {
+		t.Errorf("Expected case-insensitive lookup of IsAdmin")
+	}
+
+	if b, err := f.GetBool("isAdmin"); err != nil || b {
+		t.Errorf("GetBool = %v, %v", b, err)
+	}
+}
+
+func TestAttributeSources(t *testing.T) {
+	s := `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+    <cas:user>jdoe</cas:user>
+    <cas:attributes>
+      <cas:email>jdoe@example.org</cas:email>
+      <cas:userAttributes>
+        <cas:attribute name="firstname">John</cas:attribute>
+        <cas:lastname>Doe</cas:lastname>
+      </cas:userAttributes>
+    </cas:attributes>
+    <cas:title>Mr.</cas:title>
+  </cas:authenticationSuccess>
+</cas:serviceResponse>`
+
+	sr, err := ParseServiceResponse([]byte(s))
+	if err != nil {
+		t.Fatalf("ParseServiceResponse failed: %v", err)
+	}
+
+	expected := map[AttributeSource]UserAttributes{
+		AttributeSourceAttributes:     {"email": {"jdoe@example.org"}},
+		AttributeSourceNamed:          {"firstname": {"John"}},
+		AttributeSourceUserAttributes: {"lastname": {"Doe"}},
+		AttributeSourceRubyCAS:        {"title": {"Mr."}},
+	}
+
+	if !reflect.DeepEqual(sr.AttributeSources, expected) {
+		t.Errorf("Expected sources %v, got %v", expected, sr.AttributeSources)
+	}
+
+	if rubycas := sr.AttributesFrom(AttributeSourceRubyCAS); !reflect.DeepEqual(rubycas, UserAttributes{"title": {"Mr."}}) {
+		t.Errorf("Unexpected RubyCAS attributes %v", rubycas)
+	}
+}
+
+func TestAttributeSentInSeveralStyles(t *testing.T) {
+	s := `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+    <cas:user>jdoe</cas:user>
+    <cas:attributes>
+      <cas:email>jdoe@example.org</cas:email>
+      <cas:userAttributes>
+        <cas:attribute name="email">john.doe@example.org</cas:attribute>
+      </cas:userAttributes>
+    </cas:attributes>
+  </cas:authenticationSuccess>
+</cas:serviceResponse>`
+
+	sr, err := ParseServiceResponse([]byte(s))
+	if err != nil {
+		t.Fatalf("ParseServiceResponse failed: %v", err)
+	}
+
+	if email := sr.Attributes.GetAll("email"); len(email) != 2 {
+		t.Errorf("Expected both email values in Attributes, got %v", email)
+	}
+
+	if email := sr.AttributesFrom(AttributeSourceAttributes).GetAll("email"); !reflect.DeepEqual(email, []string{"jdoe@example.org"}) {
+		t.Errorf("Unexpected cas:attributes email %v", email)
+	}
+
+	if email := sr.AttributesFrom(AttributeSourceNamed).GetAll("email"); !reflect.DeepEqual(email, []string{"john.doe@example.org"}) {
+		t.Errorf("Unexpected named email %v", email)
+	}
+
+	if ua := sr.AttributesFrom(AttributeSourceUserAttributes); len(ua) != 0 {
+		t.Errorf("Expected no cas:userAttributes children, got %v", ua)
+	}
+}
@@ -10,6 +10,7 @@ type xmlServiceResponse struct {
 
 	Failure *xmlAuthenticationFailure
 	Success *xmlAuthenticationSuccess
+	Unknown []*xmlAnyAttribute `xml:",any"`
 }
 
 type xmlAuthenticationFailure struct {